@@ -3,17 +3,27 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
 )
 
 type Config struct {
 	ApiFetchInterval int `json:"api_fetch_interval"` // in seconds
 	EnabledApiEndpoints []string `json:"enabled_api_endpoints"`
+	Sources []SourceConfig `json:"sources"`
 	PersistentDataFilePath string `json:"datafile"`
 	LogLevel int `json:"loglevel"`
+	Filters map[string]Filter `json:"filters"` // named filters, can be referenced by id
 	Lists *[]NotifyList `json:"lists"`
+	Recipients []Recipient `json:"recipients"`
+	HttpConfiguration HttpSettings `json:"http"`
+	Enrichment EnrichmentSettings `json:"enrichment"`
 	SmtpConfiguration SmtpSettings `json:"smtp"`
+	Portal PortalSettings `json:"portal"`
 	Template MailTemplateConfig `json:"template"`
+	ReloadOnChange bool `json:"reload_on_change"` // reload when the file changes, in addition to SIGHUP
 }
 
 func NewConfig() Config {
@@ -21,46 +31,113 @@ func NewConfig() Config {
 	c := Config{
 		ApiFetchInterval: 60 * 10, // every 10 minutes,
 		EnabledApiEndpoints: []string{"bay", "bund"},
+		Sources: []SourceConfig{},
 		PersistentDataFilePath: "data.json",
 		LogLevel: 2,
+		Filters: map[string]Filter{},
 		Lists: &[]NotifyList{
 			{ Name: "Example List",
 			  Recipients: []string{},
-			  Filter: []Filter{},},
+			  Filter: []Filter{},
+			  Exclude: []Filter{},
+			  Inventory: []string{},},
 		},
+		Recipients: []Recipient{},
+		HttpConfiguration: HttpSettings{
+			Timeout: 60,
+			Proxy: "",
+			CaFile: "",
+			TlsMinVersion: "1.2",
+			UserAgent: "",
+			RecordDir: "",
+			ReplayDir: ""},
+		Enrichment: EnrichmentSettings{
+			KevFile: "",
+			EpssFile: "",
+			NvdFiles: []string{},
+			KevUrl: "",
+			EpssUrl: "",
+			RefreshCommand: "",
+			RefreshInterval: 60 * 60 * 24},
 		SmtpConfiguration: SmtpSettings{
 			From: "user@localhost",
 			User: "user@localhost",
 			Password: "change me :)",
 			ServerHost: "127.0.0.1",
 			ServerPort: 587},
+		Portal: PortalSettings{
+			Listen: "",
+			BaseUrl: "",
+			Secret: "",
+			SubscriptionsFile: "subscriptions.json",
+			AllowedDomains: []string{}},
 		Template: MailTemplateConfig{
 			SubjectTemplate: "",
 			BodyTemplate: "",
 		},
+		ReloadOnChange: false,
 	}
 	return c
 }
 
-func checkConfig(config Config) {
-	if len(*config.Lists) < 1 {
-		logger.error("Configuration is incomplete")
-		panic(errors.New("no lists are configured"))
+// loadConfig reads the configuration file, or creates it with the initial
+// configuration if it doesn't exist. JSON errors include the position.
+func loadConfig(path string) (Config, error) {
+	ds := DataStore{filepath: path, data: NewConfig(), prettyJSON: true, fileMode: 0600}
+	err := ds.init()
+	if err == nil {
+		c := ds.data.(Config)
+		if c.Lists == nil {
+			c.Lists = &[]NotifyList{}
+		}
+		return c, nil
+	}
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	offset := int64(-1)
+	if errors.As(err, &syntaxError) {
+		offset = syntaxError.Offset
+	} else if errors.As(err, &typeError) {
+		offset = typeError.Offset
+		err = fmt.Errorf("%v: expected %v, got %v", typeError.Field, typeError.Type, typeError.Value)
 	}
-	for _, l := range *config.Lists {
-		if len(l.Filter) < 1 {
-			logger.error("Configuration is incomplete")
-			panic(errors.New("list " + l.Name + " has no filter defined - at least [{'any': true/false}] should be configured"))
+	data, readErr := os.ReadFile(path)
+	if offset < 0 || readErr != nil {
+		return Config{}, err
+	}
+	line, column := 1, 1
+	for _, c := range data[:min(offset, int64(len(data)))] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
 		}
-		for _, r := range l.Recipients {
-			if !mailAddressIsValid(r) {
-				logger.error("Configuration includes invalid data")
-				panic(errors.New("'" + r + "' is not a valid e-mail address"))
-			}
+	}
+	return Config{}, fmt.Errorf("line %v, column %v: %v", line, column, err)
+}
+
+// logConfigProblems logs the problems and returns the number of errors
+func logConfigProblems(problems []ConfigProblem) int {
+	errors := 0
+	for _, p := range problems {
+		if p.Warning {
+			logger.warn("Configuration: " + p.String())
+		} else {
+			logger.error("Configuration: " + p.String())
+			errors++
 		}
 	}
-	if !mailAddressIsValid(config.SmtpConfiguration.From) {
-		logger.error("Configuration includes invalid data")
-		panic(errors.New("'" + config.SmtpConfiguration.From + "' is not a valid e-mail address"))
+	return errors
+}
+
+// checkConfig logs all problems of the configuration and exits if it is
+// invalid. Returns the registry of named filters.
+func checkConfig(config *Config) FilterRegistry {
+	filterRegistry, problems := validateConfig(config)
+	if errors := logConfigProblems(problems); errors > 0 {
+		logger.error(fmt.Sprintf("Configuration is invalid (%v errors)", errors))
+		os.Exit(1)
 	}
+	return filterRegistry
 }
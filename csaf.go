@@ -0,0 +1,388 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CSAF 2.0 provider (https://docs.oasis-open.org/csaf/csaf/v2.0/csaf-v2.0.html),
+// e.g. https://wid.cert-bund.de/.well-known/csaf/provider-metadata.json
+
+type CsafSource struct {
+	Id string
+	MetadataUrl string
+	VerifySignatures bool
+	Keyring string
+}
+
+type csafProviderMetadata struct {
+	Distributions []struct {
+		DirectoryUrl string `json:"directory_url"`
+		Rolie struct {
+			Feeds []struct {
+				Url string `json:"url"`
+			} `json:"feeds"`
+		} `json:"rolie"`
+	} `json:"distributions"`
+}
+
+type rolieFeed struct {
+	Feed struct {
+		Entry []struct {
+			Id string `json:"id"`
+			Updated time.Time `json:"updated"`
+			Link []struct {
+				Rel string `json:"rel"`
+				Href string `json:"href"`
+			} `json:"link"`
+			Content struct {
+				Src string `json:"src"`
+			} `json:"content"`
+		} `json:"entry"`
+	} `json:"feed"`
+}
+
+// a document that has to be downloaded
+type csafDocumentRef struct {
+	url string
+	hashUrl string
+	signatureUrl string
+	updated time.Time
+}
+
+type csafProduct struct {
+	Name string `json:"name"`
+	ProductIdentificationHelper struct {
+		Cpe string `json:"cpe"`
+	} `json:"product_identification_helper"`
+}
+
+type csafBranch struct {
+	Name string `json:"name"`
+	Product *csafProduct `json:"product"`
+	Branches []csafBranch `json:"branches"`
+}
+
+type csafDocument struct {
+	Document struct {
+		Title string `json:"title"`
+		AggregateSeverity struct {
+			Text string `json:"text"`
+		} `json:"aggregate_severity"`
+		References []struct {
+			Category string `json:"category"`
+			Url string `json:"url"`
+		} `json:"references"`
+		Tracking struct {
+			Id string `json:"id"`
+			InitialReleaseDate time.Time `json:"initial_release_date"`
+			RevisionHistory []json.RawMessage `json:"revision_history"`
+		} `json:"tracking"`
+	} `json:"document"`
+	ProductTree struct {
+		Branches []csafBranch `json:"branches"`
+		FullProductNames []csafProduct `json:"full_product_names"`
+	} `json:"product_tree"`
+	Vulnerabilities []struct {
+		Cve string `json:"cve"`
+		Scores []struct {
+			CvssV3 struct {
+				BaseScore float64 `json:"baseScore"`
+			} `json:"cvss_v3"`
+		} `json:"scores"`
+		Remediations []struct {
+			Category string `json:"category"`
+		} `json:"remediations"`
+	} `json:"vulnerabilities"`
+}
+
+func (s CsafSource) sourceId() string {
+	return s.Id
+}
+
+func (s CsafSource) sourceName() string {
+	return "CSAF source '" + s.Id + "'"
+}
+
+func (s CsafSource) getNotices(client *HttpClient, since time.Time) ([]WidNotice, time.Time, error) {
+	// returns a slice of WidNotice for all documents updated after since (new ones and revisions),
+	// the 'updated' timestamp of the last document, and the error (or nil)
+	notices := []WidNotice{}
+	lastUpdated := since
+	client = client.forUrl(s.MetadataUrl)
+	refs, err := s.discoverDocuments(client, since)
+	if err != nil { return notices, since, err }
+	for _, r := range refs {
+		data, err := client.get(r.url)
+		if err != nil {
+			// the feeds must be downloaded again on the next try
+			client.forgetValidators()
+			return []WidNotice{}, since, err
+		}
+		if err = s.verifyDocument(client, r, data); err != nil {
+			// don't retry, this won't get any better
+			logger.error("Skipping CSAF document " + r.url)
+			logger.error(err)
+			continue
+		}
+		n, err := s.parseDocument(data, r.url)
+		if err != nil {
+			logger.error("Skipping CSAF document " + r.url)
+			logger.error(err)
+			continue
+		}
+		notices = append(notices, n)
+		if r.updated.After(lastUpdated) {
+			lastUpdated = r.updated
+		}
+	}
+	return notices, lastUpdated, nil
+}
+
+func (s CsafSource) discoverDocuments(client *HttpClient, since time.Time) ([]csafDocumentRef, error) {
+	refs := []csafDocumentRef{}
+	data, err := client.get(s.MetadataUrl)
+	if err != nil { return refs, err }
+	metadata := csafProviderMetadata{}
+	if err = json.Unmarshal(data, &metadata); err != nil { return refs, err }
+	for _, d := range metadata.Distributions {
+		for _, f := range d.Rolie.Feeds {
+			r, err := s.rolieDocuments(client, resolveUrl(s.MetadataUrl, f.Url), since)
+			if err != nil { return refs, err }
+			refs = append(refs, r...)
+		}
+		if d.DirectoryUrl != "" && len(d.Rolie.Feeds) == 0 {
+			r, err := s.directoryDocuments(client, resolveUrl(s.MetadataUrl, d.DirectoryUrl), since)
+			if err != nil { return refs, err }
+			refs = append(refs, r...)
+		}
+	}
+	// the same document can be listed in multiple feeds
+	slices.SortFunc(refs, func(a csafDocumentRef, b csafDocumentRef) int {
+		return strings.Compare(a.url, b.url)
+	})
+	refs = slices.CompactFunc(refs, func(a csafDocumentRef, b csafDocumentRef) bool {
+		return a.url == b.url
+	})
+	slices.SortStableFunc(refs, func(a csafDocumentRef, b csafDocumentRef) int {
+		return a.updated.Compare(b.updated)
+	})
+	logger.debug(fmt.Sprintf("Found %v updated CSAF documents for source '%v'", len(refs), s.Id))
+	return refs, nil
+}
+
+func (s CsafSource) rolieDocuments(client *HttpClient, feedUrl string, since time.Time) ([]csafDocumentRef, error) {
+	refs := []csafDocumentRef{}
+	data, modified, err := client.getIfModified(feedUrl)
+	if err != nil || !modified { return refs, err }
+	feed := rolieFeed{}
+	if err = json.Unmarshal(data, &feed); err != nil { return refs, err }
+	for _, e := range feed.Feed.Entry {
+		if !e.Updated.After(since) {
+			continue
+		}
+		r := csafDocumentRef{url: e.Content.Src, updated: e.Updated}
+		for _, l := range e.Link {
+			switch l.Rel {
+			case "self":
+				if r.url == "" { r.url = l.Href }
+			case "hash":
+				// prefer sha512
+				if r.hashUrl == "" || strings.HasSuffix(l.Href, ".sha512") {
+					r.hashUrl = l.Href
+				}
+			case "signature":
+				r.signatureUrl = l.Href
+			}
+		}
+		if r.url == "" {
+			logger.warn("ROLIE entry " + e.Id + " has no document url")
+			continue
+		}
+		r.url = resolveUrl(feedUrl, r.url)
+		if r.hashUrl != "" { r.hashUrl = resolveUrl(feedUrl, r.hashUrl) }
+		if r.signatureUrl != "" { r.signatureUrl = resolveUrl(feedUrl, r.signatureUrl) }
+		refs = append(refs, r)
+	}
+	return refs, nil
+}
+
+func (s CsafSource) directoryDocuments(client *HttpClient, directoryUrl string, since time.Time) ([]csafDocumentRef, error) {
+	// changes.csv contains lines of "<path>","<timestamp>"
+	refs := []csafDocumentRef{}
+	directoryUrl = strings.TrimSuffix(directoryUrl, "/") + "/"
+	data, modified, err := client.getIfModified(directoryUrl + "changes.csv")
+	if err != nil || !modified { return refs, err }
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil { return refs, err }
+	for _, rec := range records {
+		if len(rec) < 2 { continue }
+		updated, err := time.Parse(time.RFC3339, rec[1])
+		if err != nil { return refs, err }
+		if !updated.After(since) { continue }
+		u := resolveUrl(directoryUrl, rec[0])
+		refs = append(refs, csafDocumentRef{
+			url: u,
+			hashUrl: u + ".sha512",
+			signatureUrl: u + ".asc",
+			updated: updated,
+		})
+	}
+	return refs, nil
+}
+
+func (s CsafSource) verifyDocument(client *HttpClient, r csafDocumentRef, data []byte) error {
+	if r.hashUrl == "" {
+		return errors.New("no hash available")
+	}
+	hashFile, err := client.get(r.hashUrl)
+	if err != nil { return err }
+	// <hex digest>  <filename>
+	fields := strings.Fields(string(hashFile))
+	if len(fields) < 1 {
+		return errors.New("empty hash file " + r.hashUrl)
+	}
+	var h hash.Hash
+	if strings.HasSuffix(r.hashUrl, ".sha512") {
+		h = sha512.New()
+	} else {
+		h = sha256.New()
+	}
+	h.Write(data)
+	if !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), fields[0]) {
+		return errors.New("hash mismatch for " + r.url)
+	}
+	if !s.VerifySignatures {
+		return nil
+	}
+	if r.signatureUrl == "" {
+		return errors.New("no signature available")
+	}
+	signature, err := client.get(r.signatureUrl)
+	if err != nil { return err }
+	return verifySignature(data, signature, s.Keyring)
+}
+
+// verifySignature checks a detached OpenPGP signature using gpgv
+func verifySignature(data []byte, signature []byte, keyring string) error {
+	dir, err := os.MkdirTemp("", "wid-notifier-")
+	if err != nil { return err }
+	defer os.RemoveAll(dir)
+	dataFile := path.Join(dir, "document")
+	signatureFile := path.Join(dir, "document.asc")
+	if err = os.WriteFile(dataFile, data, 0600); err != nil { return err }
+	if err = os.WriteFile(signatureFile, signature, 0600); err != nil { return err }
+	out, err := exec.Command("gpgv", "--keyring", keyring, signatureFile, dataFile).CombinedOutput()
+	if err != nil {
+		return errors.New("signature verification failed: " + strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (s CsafSource) parseDocument(data []byte, documentUrl string) (WidNotice, error) {
+	d := csafDocument{}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return WidNotice{}, err
+	}
+	if d.Document.Tracking.Id == "" {
+		return WidNotice{}, errors.New("document has no tracking id")
+	}
+	n := WidNotice{
+		Uuid: s.Id + ":" + d.Document.Tracking.Id,
+		Name: d.Document.Tracking.Id,
+		Title: d.Document.Title,
+		Published: d.Document.Tracking.InitialReleaseDate,
+		Classification: strings.ToLower(d.Document.AggregateSeverity.Text),
+		Basescore: -1,
+		Status: "NEU",
+		ApiEndpointId: s.Id,
+		PortalUrl: documentUrl,
+	}
+	if len(d.Document.Tracking.RevisionHistory) > 1 {
+		n.Status = "UPDATE"
+		// only documents updated after the cursor are fetched, so this is a
+		// revision even if the fingerprint was already pruned
+		n.Event = "changed"
+	} else {
+		// the initial release date can be older than the cursor
+		n.Event = "new"
+	}
+	for _, r := range d.Document.References {
+		if r.Category == "self" && strings.HasPrefix(r.Url, "http") {
+			n.PortalUrl = r.Url
+			break
+		}
+	}
+	// products
+	addProduct := func(p csafProduct) {
+		if !slices.Contains(n.ProductNames, p.Name) {
+			n.ProductNames = append(n.ProductNames, p.Name)
+		}
+		if cpe := p.ProductIdentificationHelper.Cpe; cpe != "" && !slices.Contains(n.Cpes, cpe) {
+			n.Cpes = append(n.Cpes, cpe)
+		}
+	}
+	var walk func(branches []csafBranch)
+	walk = func(branches []csafBranch) {
+		for _, b := range branches {
+			if b.Product != nil {
+				addProduct(*b.Product)
+			}
+			walk(b.Branches)
+		}
+	}
+	walk(d.ProductTree.Branches)
+	for _, p := range d.ProductTree.FullProductNames {
+		addProduct(p)
+	}
+	// vulnerabilities
+	vendorFix := false
+	for _, v := range d.Vulnerabilities {
+		if v.Cve != "" && !slices.Contains(n.Cves, v.Cve) {
+			n.Cves = append(n.Cves, v.Cve)
+		}
+		for _, sc := range v.Scores {
+			// WID uses 0 - 100
+			if score := int(sc.CvssV3.BaseScore * 10); score > n.Basescore {
+				n.Basescore = score
+			}
+		}
+		for _, r := range v.Remediations {
+			if r.Category == "vendor_fix" {
+				vendorFix = true
+			}
+		}
+	}
+	if len(d.Vulnerabilities) > 0 {
+		if vendorFix {
+			n.NoPatch = "false"
+		} else {
+			n.NoPatch = "true"
+		}
+	}
+	return n, nil
+}
+
+func resolveUrl(base string, ref string) string {
+	b, err := url.Parse(base)
+	if err != nil { return ref }
+	r, err := url.Parse(ref)
+	if err != nil { return ref }
+	return b.ResolveReference(r).String()
+}
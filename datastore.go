@@ -42,6 +42,11 @@ func (ds *DataStore) load() error {
 		err = json.Unmarshal(data, &d)
 		if err != nil { return err }
 		ds.data = d
+	case SubscriptionData:
+		d, _ := ds.data.(SubscriptionData);
+		err = json.Unmarshal(data, &d)
+		if err != nil { return err }
+		ds.data = d
 	}
 	return err
 }
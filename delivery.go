@@ -0,0 +1,234 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezones on systems without zoneinfo
+)
+
+// Delivery windows: notices are only sent within the windows of a list,
+// other notices are held in the data file and released when the next
+// window opens
+
+type DeliveryWindow struct {
+	Days []string `json:"days"` // "mon", "tue", ..., empty = every day
+	From string `json:"from"` // "08:00"
+	To string `json:"to"` // "17:00", windows can span midnight (e.g. "22:00" - "06:00")
+	weekdays []time.Weekday
+	from int // minutes since midnight
+	to int
+}
+
+type DeliverySettings struct {
+	Timezone string `json:"timezone"` // e.g. "Europe/Berlin", empty = local time
+	Windows []DeliveryWindow `json:"windows"` // empty = always, except on holidays
+	HolidaysFile string `json:"holidays_file"` // one date (YYYY-MM-DD) per line
+	// classifications that are delivered immediately, default: ["kritisch"]
+	Bypass []string `json:"bypass"`
+	location *time.Location
+	holidays []string // YYYY-MM-DD
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s == "24:00" { return 24 * 60, nil }
+		return 0, errors.New("invalid time of day '" + s + "', expected HH:MM")
+	}
+	return t.Hour() * 60 + t.Minute(), nil
+}
+
+func (d *DeliverySettings) compile() error {
+	var err error
+	d.location = time.Local
+	if d.Timezone != "" {
+		d.location, err = time.LoadLocation(d.Timezone)
+		if err != nil { return err }
+	}
+	for i := range d.Windows {
+		w := &d.Windows[i]
+		w.weekdays = []time.Weekday{}
+		for _, day := range w.Days {
+			day = strings.ToLower(strings.TrimSpace(day))
+			if len(day) >= 3 { day = day[:3] }
+			wd := slices.Index(weekdayNames, day)
+			if wd < 0 {
+				return fmt.Errorf("window %v: unknown day '%v'", i, day)
+			}
+			w.weekdays = append(w.weekdays, time.Weekday(wd))
+		}
+		if w.from, err = parseTimeOfDay(w.From); err != nil { return fmt.Errorf("window %v: %v", i, err) }
+		if w.to, err = parseTimeOfDay(w.To); err != nil { return fmt.Errorf("window %v: %v", i, err) }
+		if w.from == w.to {
+			return fmt.Errorf("window %v is empty", i)
+		}
+	}
+	d.holidays = []string{}
+	if d.HolidaysFile != "" {
+		data, err := os.ReadFile(d.HolidaysFile)
+		if err != nil { return err }
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			date := strings.Fields(line)[0]
+			if _, err := time.Parse(time.DateOnly, date); err != nil {
+				return errors.New(d.HolidaysFile + ": invalid date '" + date + "'")
+			}
+			d.holidays = append(d.holidays, date)
+		}
+	}
+	if d.Bypass == nil {
+		d.Bypass = []string{"kritisch"}
+	}
+	return nil
+}
+
+func (d *DeliverySettings) holiday(t time.Time) bool {
+	return slices.Contains(d.holidays, t.Format(time.DateOnly))
+}
+
+func (w DeliveryWindow) contains(t time.Time, d *DeliverySettings) bool {
+	minutes := t.Hour() * 60 + t.Minute()
+	day := t
+	if w.from < w.to {
+		if minutes < w.from || minutes >= w.to { return false }
+	} else if minutes < w.to {
+		// the window started the day before
+		day = t.AddDate(0, 0, -1)
+	} else if minutes < w.from {
+		return false
+	}
+	if len(w.weekdays) > 0 && !slices.Contains(w.weekdays, day.Weekday()) {
+		return false
+	}
+	return !d.holiday(day)
+}
+
+// open checks if notices can be delivered at the given time
+func (d *DeliverySettings) open(t time.Time) bool {
+	t = t.In(d.location)
+	if len(d.Windows) < 1 {
+		return !d.holiday(t)
+	}
+	for _, w := range d.Windows {
+		if w.contains(t, d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DeliverySettings) bypasses(n WidNotice) bool {
+	return slices.Contains(d.Bypass, n.Classification)
+}
+
+// latestVersion returns the revision n of the held notice h. The recipients
+// didn't get h yet, so a held new notice stays new.
+func latestVersion(h WidNotice, n WidNotice) WidNotice {
+	if h.Event == "new" {
+		n.Event = "new"
+		n.Changes = nil
+	}
+	return n
+}
+
+// deliverableNotices returns the notices of the list that can be sent now,
+// including previously held notices if a delivery window is open. The other
+// notices are added to held (list name : notices).
+func (l NotifyList) deliverableNotices(notices []WidNotice, held map[string][]WidNotice, now time.Time) []WidNotice {
+	if l.Delivery == nil || l.Delivery.open(now) {
+		if len(held[l.Name]) > 0 {
+			logger.info(fmt.Sprintf("Releasing %v held notices for list '%v'", len(held[l.Name]), l.Name))
+			released := slices.Clone(held[l.Name])
+			for _, n := range notices {
+				i := slices.IndexFunc(released, func(h WidNotice) bool { return h.Uuid == n.Uuid })
+				if i < 0 {
+					released = append(released, n)
+				} else {
+					released[i] = latestVersion(released[i], n)
+				}
+			}
+			notices = released
+			delete(held, l.Name)
+		}
+		return notices
+	}
+	deliverable := []WidNotice{}
+	for _, n := range notices {
+		i := slices.IndexFunc(held[l.Name], func(h WidNotice) bool { return h.Uuid == n.Uuid })
+		if l.Delivery.bypasses(n) {
+			if i >= 0 {
+				// sent now instead of the held version
+				n = latestVersion(held[l.Name][i], n)
+				held[l.Name] = slices.Delete(held[l.Name], i, i + 1)
+				if len(held[l.Name]) < 1 {
+					delete(held, l.Name)
+				}
+			}
+			deliverable = append(deliverable, n)
+			continue
+		}
+		if i < 0 {
+			held[l.Name] = append(held[l.Name], n)
+			continue
+		}
+		// a held notice was revised, the recipients only get the latest version
+		held[l.Name][i] = latestVersion(held[l.Name][i], n)
+	}
+	if len(notices) > len(deliverable) {
+		logger.debug(fmt.Sprintf("Holding %v notices for list '%v' until the next delivery window", len(notices) - len(deliverable), l.Name))
+	}
+	return deliverable
+}
+
+func isHeld(held map[string][]WidNotice, uuid string) bool {
+	for _, notices := range held {
+		if slices.ContainsFunc(notices, func(n WidNotice) bool { return n.Uuid == uuid }) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSent remembers which version of the held notices was sent to the
+// recipient through another list, so it isn't sent again on release
+func recordSent(sent map[string]map[string]string, held map[string][]WidNotice, address string, notices []*WidNotice) {
+	address = strings.ToLower(address)
+	for _, n := range notices {
+		if !isHeld(held, n.Uuid) { continue }
+		if sent[address] == nil {
+			sent[address] = map[string]string{}
+		}
+		sent[address][n.Uuid] = NewNoticeFingerprint(*n).version()
+	}
+}
+
+// alreadySent checks if the recipient already got this version of the notice
+func alreadySent(sent map[string]map[string]string, address string, n WidNotice) bool {
+	v, ok := sent[strings.ToLower(address)][n.Uuid]
+	return ok && v == NewNoticeFingerprint(n).version()
+}
+
+// pruneSent forgets the notices that aren't held anymore
+func pruneSent(sent map[string]map[string]string, held map[string][]WidNotice) {
+	for address, notices := range sent {
+		for uuid := range notices {
+			if !isHeld(held, uuid) {
+				delete(notices, uuid)
+			}
+		}
+		if len(notices) < 1 {
+			delete(sent, address)
+		}
+	}
+}
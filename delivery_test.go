@@ -0,0 +1,167 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSentHeldNotices(t *testing.T) {
+	n := WidNotice{Uuid: "rss:1", Name: "1", Title: "Jenkins", Basescore: 50}
+	revised := n
+	revised.Basescore = 70
+	sent := map[string]map[string]string{}
+	// cycle 1: sent to r through list A, held for list B
+	held := map[string][]WidNotice{"B": {n}}
+	recordSent(sent, held, "R@example.org", []*WidNotice{&n})
+	// notices that aren't held aren't remembered
+	other := WidNotice{Uuid: "rss:2"}
+	recordSent(sent, held, "r@example.org", []*WidNotice{&other})
+	tests := []struct {
+		name string
+		address string
+		notice WidNotice
+		want bool
+	}{
+		{"same version", "r@example.org", n, true},
+		{"address is case-insensitive", "r@EXAMPLE.org", n, true},
+		{"other recipient", "s@example.org", n, false},
+		{"revised version", "r@example.org", revised, false},
+		{"not held", "r@example.org", other, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alreadySent(sent, tt.address, tt.notice); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// cycle 2: released
+	pruneSent(sent, map[string][]WidNotice{})
+	if len(sent) > 0 {
+		t.Errorf("got %v after the release, want nothing", sent)
+	}
+}
+
+func TestDeliveryWindows(t *testing.T) {
+	holidays := filepath.Join(t.TempDir(), "holidays.txt")
+	if err := os.WriteFile(holidays, []byte("# comment\n2026-10-20 Tuesday\n2026-10-23\n"), 0644); err != nil { t.Fatal(err) }
+	d := DeliverySettings{
+		Timezone: "Europe/Berlin",
+		Windows: []DeliveryWindow{
+			{Days: []string{"fri"}, From: "22:00", To: "06:00"}, // Friday night until Saturday morning
+			{Days: []string{"Monday", "tue"}, From: "08:00", To: "17:00"},
+		},
+		HolidaysFile: holidays,
+	}
+	if err := d.compile(); err != nil { t.Fatal(err) }
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil { t.Fatal(err) }
+	at := func(day int, hour int, minute int, loc *time.Location) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, loc)
+	}
+	tests := []struct {
+		name string
+		t time.Time
+		window int // index of the window that contains t, -1 = closed
+	}{
+		{"friday evening", at(16, 22, 30, berlin), 0},
+		{"friday before the window", at(16, 21, 59, berlin), -1},
+		{"saturday morning, started on friday", at(17, 5, 59, berlin), 0},
+		{"saturday, end of the window", at(17, 6, 0, berlin), -1},
+		{"saturday evening", at(17, 22, 30, berlin), -1},
+		{"sunday night, started on saturday", at(18, 2, 0, berlin), -1},
+		{"monday, start of the window", at(19, 8, 0, berlin), 1},
+		{"monday, end of the window", at(19, 17, 0, berlin), -1},
+		{"tuesday, holiday", at(20, 10, 0, berlin), -1},
+		{"saturday morning, started on a holiday", at(24, 2, 0, berlin), -1},
+		// the windows are in the timezone of the settings
+		{"friday evening in berlin, utc", at(16, 20, 30, time.UTC), 0},
+		{"saturday morning in berlin, friday in utc", at(16, 22, 30, time.UTC), 0},
+		{"saturday morning in utc, after the window in berlin", at(17, 4, 30, time.UTC), -1},
+		{"monday in utc, holiday in berlin", at(19, 23, 30, time.UTC), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, w := range d.Windows {
+				if got := w.contains(tt.t.In(d.location), &d); got != (i == tt.window) {
+					t.Errorf("window %v: got contains = %v", i, got)
+				}
+			}
+			if got := d.open(tt.t); got != (tt.window >= 0) {
+				t.Errorf("got open = %v, want %v", got, tt.window >= 0)
+			}
+		})
+	}
+	// without windows, only holidays are closed
+	d.Windows = nil
+	for _, tt := range []struct{ t time.Time; want bool }{
+		{at(19, 12, 0, berlin), true},
+		{at(20, 12, 0, berlin), false},
+		{at(19, 23, 30, time.UTC), false},
+	} {
+		if got := d.open(tt.t); got != tt.want {
+			t.Errorf("%v without windows: got open = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestDeliverableNotices(t *testing.T) {
+	logger = NewLogger(0)
+	d := &DeliverySettings{Windows: []DeliveryWindow{{From: "08:00", To: "17:00"}}}
+	if err := d.compile(); err != nil { t.Fatal(err) }
+	l := NotifyList{Name: "A", Delivery: d}
+	night := time.Date(2026, 10, 19, 2, 0, 0, 0, time.Local)
+	day := time.Date(2026, 10, 19, 10, 0, 0, 0, time.Local)
+	notice := WidNotice{Uuid: "1", Classification: "hoch", Basescore: 70, Event: "new"}
+	revised := WidNotice{Uuid: "1", Classification: "hoch", Basescore: 80, Event: "changed", Changes: []FieldChange{{Field: "basescore", Old: "70", New: "80"}}}
+	critical := WidNotice{Uuid: "1", Classification: "kritisch", Basescore: 95, Event: "changed", Changes: []FieldChange{{Field: "classification", Old: "hoch", New: "kritisch"}}}
+	other := WidNotice{Uuid: "2", Classification: "mittel", Event: "new"}
+	check := func(t *testing.T, got []WidNotice, want []WidNotice) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		for i := range got {
+			if got[i].Uuid != want[i].Uuid || got[i].Basescore != want[i].Basescore || got[i].Event != want[i].Event || len(got[i].Changes) != len(want[i].Changes) {
+				t.Errorf("got %+v, want %+v", got[i], want[i])
+			}
+		}
+	}
+	asNew := func(n WidNotice) WidNotice {
+		n.Event = "new"
+		n.Changes = nil
+		return n
+	}
+	t.Run("held and revised in the window", func(t *testing.T) {
+		held := map[string][]WidNotice{}
+		check(t, l.deliverableNotices([]WidNotice{notice, other}, held, night), []WidNotice{})
+		check(t, l.deliverableNotices([]WidNotice{revised}, held, day), []WidNotice{asNew(revised), other})
+		if len(held) > 0 {
+			t.Errorf("got held %v, want nothing", held)
+		}
+	})
+	t.Run("held and revised outside the window", func(t *testing.T) {
+		held := map[string][]WidNotice{}
+		l.deliverableNotices([]WidNotice{notice}, held, night)
+		l.deliverableNotices([]WidNotice{revised}, held, night)
+		check(t, held["A"], []WidNotice{asNew(revised)})
+	})
+	t.Run("held and raised to a bypassing classification", func(t *testing.T) {
+		held := map[string][]WidNotice{}
+		l.deliverableNotices([]WidNotice{notice, other}, held, night)
+		check(t, l.deliverableNotices([]WidNotice{critical}, held, night), []WidNotice{asNew(critical)})
+		check(t, held["A"], []WidNotice{other})
+		check(t, l.deliverableNotices([]WidNotice{}, held, day), []WidNotice{other})
+	})
+	t.Run("bypassing and not held", func(t *testing.T) {
+		held := map[string][]WidNotice{}
+		check(t, l.deliverableNotices([]WidNotice{critical}, held, night), []WidNotice{critical})
+		if len(held) > 0 {
+			t.Errorf("got held %v, want nothing", held)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Enrichment of the CVEs of notices with data from locally mirrored files:
+// - CISA Known Exploited Vulnerabilities (JSON)
+// - EPSS scores (CSV, optionally gzipped)
+// - NVD CVE API 2.0 responses / feeds (JSON) for CVSS vectors
+
+// the refresh command is killed after this time
+const REFRESH_COMMAND_TIMEOUT = time.Minute * 10
+
+type EnrichmentSettings struct {
+	KevFile string `json:"kev_file"`
+	EpssFile string `json:"epss_file"`
+	NvdFiles []string `json:"nvd_files"`
+	// refresh
+	KevUrl string `json:"kev_url"` // downloaded to kev_file
+	EpssUrl string `json:"epss_url"` // downloaded to epss_file
+	RefreshCommand string `json:"refresh_command"` // run with sh -c
+	RefreshInterval int `json:"refresh_interval"` // in seconds, 0 = only on startup
+}
+
+type CveDetails struct {
+	Id string
+	Kev bool // in the CISA KEV catalog
+	KevDateAdded string
+	KevRansomware bool // known to be used in ransomware campaigns
+	Epss float64 // probability of exploitation in the next 30 days (0 - 1)
+	EpssPercentile float64
+	CvssVector string
+	CvssScore float64
+}
+
+type kevCatalog struct {
+	Vulnerabilities []struct {
+		CveId string `json:"cveID"`
+		DateAdded string `json:"dateAdded"`
+		KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse"`
+	} `json:"vulnerabilities"`
+}
+
+type cvssMetric struct {
+	CvssData struct {
+		VectorString string `json:"vectorString"`
+		BaseScore float64 `json:"baseScore"`
+	} `json:"cvssData"`
+}
+
+type nvdDocument struct {
+	Vulnerabilities []struct {
+		Cve struct {
+			Id string `json:"id"`
+			Metrics struct {
+				CvssMetricV40 []cvssMetric `json:"cvssMetricV40"`
+				CvssMetricV31 []cvssMetric `json:"cvssMetricV31"`
+				CvssMetricV30 []cvssMetric `json:"cvssMetricV30"`
+			} `json:"metrics"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+type Enricher struct {
+	settings EnrichmentSettings
+	details map[string]CveDetails // cve id : details
+	lastRefresh time.Time
+}
+
+func NewEnricher(s EnrichmentSettings) *Enricher {
+	return &Enricher{settings: s, details: map[string]CveDetails{}}
+}
+
+func (e *Enricher) enabled() bool {
+	return e.settings.KevFile != "" || e.settings.EpssFile != "" || len(e.settings.NvdFiles) > 0
+}
+
+// refresh updates and reloads the files if the refresh interval elapsed
+func (e *Enricher) refresh(client *HttpClient) {
+	if !e.enabled() { return }
+	if !e.lastRefresh.IsZero() && (e.settings.RefreshInterval <= 0 || time.Since(e.lastRefresh) < time.Duration(e.settings.RefreshInterval) * time.Second) {
+		return
+	}
+	e.lastRefresh = time.Now()
+	logger.debug("Refreshing enrichment data ...")
+	download := func(url string, file string) {
+		if url == "" || file == "" { return }
+		data, err := client.forUrl(url).get(url)
+		if err == nil {
+			err = os.WriteFile(file, data, 0640)
+		}
+		if err != nil {
+			logger.error("Couldn't download " + url + " to " + file)
+			logger.error(err)
+		}
+	}
+	download(e.settings.KevUrl, e.settings.KevFile)
+	download(e.settings.EpssUrl, e.settings.EpssFile)
+	if e.settings.RefreshCommand != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), REFRESH_COMMAND_TIMEOUT)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "sh", "-c", e.settings.RefreshCommand)
+		// don't wait for processes started by the command that keep the output open
+		cmd.WaitDelay = time.Second * 10
+		out, err := cmd.CombinedOutput()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %v", REFRESH_COMMAND_TIMEOUT)
+		}
+		if err != nil {
+			logger.error("Enrichment refresh command failed")
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				logger.error(msg)
+			}
+			logger.error(err)
+		}
+	}
+	details, err := e.load()
+	if err != nil {
+		// keep the old data
+		logger.error("Couldn't load enrichment data")
+		logger.error(err)
+		return
+	}
+	e.details = details
+	logger.debug(fmt.Sprintf("Loaded enrichment data for %v CVEs", len(details)))
+}
+
+func (e *Enricher) load() (map[string]CveDetails, error) {
+	details := map[string]CveDetails{}
+	get := func(id string) CveDetails {
+		d, ok := details[id]
+		if !ok { d.Id = id }
+		return d
+	}
+	if e.settings.KevFile != "" {
+		data, err := readMaybeGzipped(e.settings.KevFile)
+		if err != nil { return nil, err }
+		catalog := kevCatalog{}
+		if err = json.Unmarshal(data, &catalog); err != nil { return nil, err }
+		for _, v := range catalog.Vulnerabilities {
+			d := get(v.CveId)
+			d.Kev = true
+			d.KevDateAdded = v.DateAdded
+			d.KevRansomware = v.KnownRansomwareCampaignUse == "Known"
+			details[v.CveId] = d
+		}
+	}
+	if e.settings.EpssFile != "" {
+		data, err := readMaybeGzipped(e.settings.EpssFile)
+		if err != nil { return nil, err }
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comment = '#' // #model_version:...
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil { return nil, err }
+		for _, r := range records {
+			if len(r) < 3 || !strings.HasPrefix(r[0], "CVE-") { continue } // header
+			epss, err1 := strconv.ParseFloat(r[1], 64)
+			percentile, err2 := strconv.ParseFloat(r[2], 64)
+			if err1 != nil || err2 != nil { continue }
+			d := get(r[0])
+			d.Epss = epss
+			d.EpssPercentile = percentile
+			details[r[0]] = d
+		}
+	}
+	for _, f := range e.settings.NvdFiles {
+		data, err := readMaybeGzipped(f)
+		if err != nil { return nil, err }
+		doc := nvdDocument{}
+		if err = json.Unmarshal(data, &doc); err != nil { return nil, err }
+		for _, v := range doc.Vulnerabilities {
+			m := v.Cve.Metrics
+			for _, metrics := range [][]cvssMetric{m.CvssMetricV31, m.CvssMetricV30, m.CvssMetricV40} {
+				if len(metrics) > 0 {
+					d := get(v.Cve.Id)
+					d.CvssVector = metrics[0].CvssData.VectorString
+					d.CvssScore = metrics[0].CvssData.BaseScore
+					details[v.Cve.Id] = d
+					break
+				}
+			}
+		}
+	}
+	return details, nil
+}
+
+// enrich annotates the notices with the details of their CVEs
+func (e *Enricher) enrich(notices []WidNotice) {
+	if !e.enabled() { return }
+	for i := range notices {
+		n := &notices[i]
+		n.CveDetails = []CveDetails{}
+		n.Kev = false
+		n.MaxEpss = 0
+		for _, c := range n.Cves {
+			d, ok := e.details[strings.ToUpper(c)]
+			if !ok {
+				d = CveDetails{Id: c}
+			}
+			n.CveDetails = append(n.CveDetails, d)
+			n.Kev = n.Kev || d.Kev
+			n.MaxEpss = max(n.MaxEpss, d.Epss)
+		}
+	}
+}
+
+func readMaybeGzipped(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil { return nil, err }
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return data, nil
+}
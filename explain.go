@@ -0,0 +1,110 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const EXPLAIN_PERIOD = time.Hour * 24 * 7
+
+// readNoticesFile reads notices from a JSON file, either a list of
+// notices or a response of the WID API
+func readNoticesFile(path string) ([]WidNotice, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	notices := []WidNotice{}
+	if err = json.Unmarshal(data, &notices); err == nil {
+		return notices, nil
+	}
+	var apiResponse map[string]interface{}
+	if err = json.Unmarshal(data, &apiResponse); err != nil { return nil, err }
+	if _, ok := apiResponse["content"].([]interface{}); !ok {
+		return nil, fmt.Errorf("%v is neither a list of notices nor an API response", path)
+	}
+	return parseApiResponse(apiResponse, ApiEndpoint{Id: "file"}), nil
+}
+
+// recentNotices queries the sources for the notices of the last EXPLAIN_PERIOD
+func recentNotices(sources []Source, client *HttpClient) []WidNotice {
+	notices := []WidNotice{}
+	since := time.Now().Add(-EXPLAIN_PERIOD)
+	for _, s := range sources {
+		logger.info("Querying " + s.sourceName() + " for notices ...")
+		// CSAF documents are only downloaded if they were updated recently
+		n, _, err := queryNotices(s, client, since)
+		if err != nil { continue }
+		// the other sources return all current notices
+		for _, x := range n {
+			if x.Published.After(since) || x.Event != "" {
+				notices = append(notices, x)
+			}
+		}
+	}
+	return notices
+}
+
+// explain shows which notices match the filters of each list and why,
+// without sending anything or touching the data file
+func explain(config Config, sources []Source, client *HttpClient, enricher *Enricher, subscriptions *SubscriptionStore, noticesFilePath string) {
+	notices := []WidNotice{}
+	if noticesFilePath != "" {
+		n, err := readNoticesFile(noticesFilePath)
+		if err != nil {
+			logger.error("Couldn't read notices from " + noticesFilePath)
+			logger.error(err)
+			os.Exit(1)
+		}
+		notices = n
+	} else {
+		notices = recentNotices(sources, client)
+	}
+	for i := range notices {
+		if notices[i].Event == "" {
+			notices[i].Event = "new"
+		}
+	}
+	enricher.refresh(client)
+	enricher.enrich(notices)
+	fmt.Printf("%v notices\n", len(notices))
+	now := time.Now()
+	listNotices := map[string][]WidNotice{} // list name : included notices
+	for _, l := range *config.Lists {
+		included := 0
+		fmt.Printf("\nList '%v'\n", l.Name)
+		for _, n := range notices {
+			m := l.matchNotice(n)
+			mark := "-"
+			if m.included {
+				mark = "+"
+				included++
+				listNotices[l.Name] = append(listNotices[l.Name], m.notice)
+			}
+			fmt.Printf("  %v %v [%v] %v (%v)\n", mark, n.Name, n.Classification, n.Title, n.ApiEndpointId)
+			if m.included && l.Delivery != nil && !l.Delivery.open(now) && !l.Delivery.bypasses(n) {
+				m.reason += ", held until the next delivery window"
+			}
+			fmt.Printf("      %v\n", m.reason)
+			for _, a := range m.notice.MatchedAssets {
+				fmt.Printf("      affects %v\n", a)
+			}
+		}
+		fmt.Printf("  %v of %v notices would be sent to %v recipients\n", included, len(notices), len(l.Recipients))
+	}
+	for _, r := range allRecipients(config, subscriptions.all()) {
+		if len(r.Lists) > 0 {
+			fmt.Printf("\nRecipient %v (lists: %v)\n", r.Address, strings.Join(r.Lists, ", "))
+		} else {
+			fmt.Printf("\nRecipient %v\n", r.Address)
+		}
+		selected := r.selectNotices(listNotices, notices)
+		for _, n := range selected {
+			fmt.Printf("  + %v [%v] %v (%v)\n", n.Name, n.Classification, n.Title, n.ApiEndpointId)
+		}
+		fmt.Printf("  %v notices would be sent\n", len(selected))
+	}
+}
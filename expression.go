@@ -0,0 +1,382 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Boolean filter expressions, e.g.
+//   classification in ("hoch", "kritisch") and not title ~ "Jenkins" and (basescore >= 70 or no_patch)
+//
+// expression := or
+// or         := and ("or" and)*
+// and        := not ("and" not)*
+// not        := "not" not | "(" expression ")" | comparison
+// comparison := field [operator value | "in" "(" value ("," value)* ")"]
+
+type exprFieldType int
+
+const (
+	exprString exprFieldType = iota
+	exprNumber
+	exprBool
+	exprList
+)
+
+type exprField struct {
+	fieldType exprFieldType
+	value func(n *WidNotice) any // string, float64, bool or []string
+}
+
+var exprFields = map[string]exprField{
+	"uuid": {exprString, func(n *WidNotice) any { return n.Uuid }},
+	"name": {exprString, func(n *WidNotice) any { return n.Name }},
+	"title": {exprString, func(n *WidNotice) any { return n.Title }},
+	"classification": {exprString, func(n *WidNotice) any { return n.Classification }},
+	"status": {exprString, func(n *WidNotice) any { return n.Status }},
+	"api_endpoint": {exprString, func(n *WidNotice) any { return n.ApiEndpointId }},
+	"event": {exprString, func(n *WidNotice) any { return n.Event }},
+	"basescore": {exprNumber, func(n *WidNotice) any { return float64(n.Basescore) }},
+	"no_patch": {exprBool, func(n *WidNotice) any { return n.NoPatch == "true" }},
+	"kev": {exprBool, func(n *WidNotice) any { return n.Kev }},
+	"epss": {exprNumber, func(n *WidNotice) any { return n.MaxEpss }},
+	"products": {exprList, func(n *WidNotice) any { return n.ProductNames }},
+	"cves": {exprList, func(n *WidNotice) any { return n.Cves }},
+	"changed": {exprList, func(n *WidNotice) any {
+		fields := []string{}
+		for _, c := range n.Changes {
+			fields = append(fields, c.Field)
+		}
+		return fields
+	}},
+}
+
+// tokens
+
+type exprTokenKind int
+
+const (
+	tokEOF exprTokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOperator
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	column int // 1-based
+}
+
+func (t exprToken) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return "'" + t.text + "'"
+}
+
+type ExpressionError struct {
+	Column int
+	Message string
+}
+
+func (e ExpressionError) Error() string {
+	return fmt.Sprintf("column %v: %v", e.Column, e.Message)
+}
+
+func tokenizeExpression(s string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	runes := []rune(s)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		col := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, exprToken{tokLParen, "(", col}); i++
+		case r == ')':
+			tokens = append(tokens, exprToken{tokRParen, ")", col}); i++
+		case r == ',':
+			tokens = append(tokens, exprToken{tokComma, ",", col}); i++
+		case r == '"':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				if runes[j] == '\\' { j++ }
+				j++
+			}
+			if j >= len(runes) {
+				return nil, ExpressionError{col, "unterminated string"}
+			}
+			text, err := strconv.Unquote(string(runes[i:j+1]))
+			if err != nil {
+				return nil, ExpressionError{col, "invalid string " + string(runes[i:j+1])}
+			}
+			tokens = append(tokens, exprToken{tokString, text, col})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') { j++ }
+			tokens = append(tokens, exprToken{tokNumber, string(runes[i:j]), col})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') { j++ }
+			tokens = append(tokens, exprToken{tokIdent, string(runes[i:j]), col})
+			i = j
+		case strings.ContainsRune("=!<>~", r):
+			j := i + 1
+			if j < len(runes) && strings.ContainsRune("=~", runes[j]) { j++ }
+			op := string(runes[i:j])
+			if !slices.Contains([]string{"==", "!=", "<", "<=", ">", ">=", "~", "!~"}, op) {
+				return nil, ExpressionError{col, "unknown operator '" + op + "'"}
+			}
+			tokens = append(tokens, exprToken{tokOperator, op, col})
+			i = j
+		default:
+			return nil, ExpressionError{col, "unexpected character '" + string(r) + "'"}
+		}
+	}
+	tokens = append(tokens, exprToken{tokEOF, "", len(runes) + 1})
+	return tokens, nil
+}
+
+// syntax tree
+
+type exprNode interface {
+	eval(n *WidNotice) bool
+}
+
+type exprOr struct{ a, b exprNode }
+type exprAnd struct{ a, b exprNode }
+type exprNot struct{ a exprNode }
+
+func (e exprOr) eval(n *WidNotice) bool { return e.a.eval(n) || e.b.eval(n) }
+func (e exprAnd) eval(n *WidNotice) bool { return e.a.eval(n) && e.b.eval(n) }
+func (e exprNot) eval(n *WidNotice) bool { return !e.a.eval(n) }
+
+type exprComparison struct {
+	field exprField
+	operator string // "" = the field itself is tested
+	values []any // string, float64, bool or *regexp.Regexp
+}
+
+func (e exprComparison) eval(n *WidNotice) bool {
+	v := e.field.value(n)
+	if e.operator == "" {
+		switch v := v.(type) {
+		case bool: return v
+		case string: return v != ""
+		case float64: return v > 0
+		case []string: return len(v) > 0
+		}
+	}
+	if l, ok := v.([]string); ok {
+		// any element matches, or none for negated operators
+		if e.operator == "!=" || e.operator == "!~" {
+			for _, x := range l {
+				if !e.compare(x) { return false }
+			}
+			return true
+		}
+		for _, x := range l {
+			if e.compare(x) { return true }
+		}
+		return false
+	}
+	return e.compare(v)
+}
+
+func (e exprComparison) compare(v any) bool {
+	for _, x := range e.values {
+		var result bool
+		switch e.operator {
+		case "==", "in": result = v == x
+		case "!=": result = v != x
+		case "~": result = x.(*regexp.Regexp).MatchString(v.(string))
+		case "!~": result = !x.(*regexp.Regexp).MatchString(v.(string))
+		case "<": result = v.(float64) < x.(float64)
+		case "<=": result = v.(float64) <= x.(float64)
+		case ">": result = v.(float64) > x.(float64)
+		case ">=": result = v.(float64) >= x.(float64)
+		}
+		if result { return true }
+	}
+	return false
+}
+
+// parser
+
+type exprParser struct {
+	tokens []exprToken
+	pos int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF { p.pos++ }
+	return t
+}
+
+func (p *exprParser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, word)
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	a, err := p.parseAnd()
+	if err != nil { return nil, err }
+	for p.isKeyword("or") {
+		p.next()
+		b, err := p.parseAnd()
+		if err != nil { return nil, err }
+		a = exprOr{a, b}
+	}
+	return a, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	a, err := p.parseNot()
+	if err != nil { return nil, err }
+	for p.isKeyword("and") {
+		p.next()
+		b, err := p.parseNot()
+		if err != nil { return nil, err }
+		a = exprAnd{a, b}
+	}
+	return a, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isKeyword("not") {
+		p.next()
+		a, err := p.parseNot()
+		if err != nil { return nil, err }
+		return exprNot{a}, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		a, err := p.parseOr()
+		if err != nil { return nil, err }
+		if t := p.next(); t.kind != tokRParen {
+			return nil, ExpressionError{t.column, "expected ')' but got " + t.String()}
+		}
+		return a, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, ExpressionError{t.column, "expected a field but got " + t.String()}
+	}
+	field, ok := exprFields[strings.ToLower(t.text)]
+	if !ok {
+		return nil, ExpressionError{t.column, "unknown field '" + t.text + "'"}
+	}
+	c := exprComparison{field: field}
+	op := p.peek()
+	if p.isKeyword("in") {
+		p.next()
+		c.operator = "in"
+		if t := p.next(); t.kind != tokLParen {
+			return nil, ExpressionError{t.column, "expected '(' after 'in' but got " + t.String()}
+		}
+		for {
+			v, err := p.parseValue(field, "in")
+			if err != nil { return nil, err }
+			c.values = append(c.values, v)
+			t := p.next()
+			if t.kind == tokRParen { break }
+			if t.kind != tokComma {
+				return nil, ExpressionError{t.column, "expected ',' or ')' but got " + t.String()}
+			}
+		}
+		return c, nil
+	}
+	if op.kind != tokOperator {
+		// the field itself
+		return c, nil
+	}
+	p.next()
+	c.operator = op.text
+	numeric := slices.Contains([]string{"<", "<=", ">", ">="}, op.text)
+	if numeric && field.fieldType != exprNumber {
+		return nil, ExpressionError{op.column, "operator '" + op.text + "' can only be used with numeric fields"}
+	}
+	if (op.text == "~" || op.text == "!~") && field.fieldType != exprString && field.fieldType != exprList {
+		return nil, ExpressionError{op.column, "operator '" + op.text + "' can only be used with text fields"}
+	}
+	v, err := p.parseValue(field, op.text)
+	if err != nil { return nil, err }
+	c.values = []any{v}
+	return c, nil
+}
+
+func (p *exprParser) parseValue(field exprField, operator string) (any, error) {
+	t := p.next()
+	switch field.fieldType {
+	case exprNumber:
+		if t.kind != tokNumber {
+			return nil, ExpressionError{t.column, "expected a number but got " + t.String()}
+		}
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, ExpressionError{t.column, "invalid number " + t.String()}
+		}
+		return v, nil
+	case exprBool:
+		if t.kind != tokIdent || (t.text != "true" && t.text != "false") {
+			return nil, ExpressionError{t.column, "expected true or false but got " + t.String()}
+		}
+		return t.text == "true", nil
+	default:
+		if t.kind != tokString {
+			return nil, ExpressionError{t.column, "expected a quoted text but got " + t.String()}
+		}
+		if operator == "~" || operator == "!~" {
+			r, err := regexp.Compile(t.text)
+			if err != nil {
+				return nil, ExpressionError{t.column, "invalid regular expression: " + err.Error()}
+			}
+			return r, nil
+		}
+		return t.text, nil
+	}
+}
+
+type FilterExpression struct {
+	root exprNode
+}
+
+func (e *FilterExpression) matches(n *WidNotice) bool {
+	return e.root.eval(n)
+}
+
+func ParseFilterExpression(s string) (*FilterExpression, error) {
+	tokens, err := tokenizeExpression(s)
+	if err != nil { return nil, err }
+	p := exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil { return nil, err }
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, ExpressionError{t.column, "expected 'and', 'or' or end of expression but got " + t.String()}
+	}
+	return &FilterExpression{root}, nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFilterExpressionEval(t *testing.T) {
+	n := WidNotice{
+		Title: "Jenkins: Mehrere Schwachstellen",
+		Classification: "hoch",
+		Basescore: 75,
+		NoPatch: "true",
+		ProductNames: []string{"Jenkins", "Jenkins LTS"},
+		Cves: []string{"CVE-2024-1", "CVE-2024-2"},
+		Kev: true,
+		MaxEpss: 0.5,
+		Event: "changed",
+		Changes: []FieldChange{{Field: "cves", Added: []string{"CVE-2024-2"}}},
+	}
+	tests := []struct {
+		expression string
+		want bool
+	}{
+		{`classification == "hoch"`, true},
+		{`classification != "hoch"`, false},
+		{`classification in ("hoch", "kritisch")`, true},
+		{`classification in ("niedrig")`, false},
+		{`title == "Jenkins: Mehrere Schwachstellen"`, true},
+		{`title ~ "^Jenkins"`, true},
+		{`title !~ "^OpenSSL"`, true},
+		{`not title ~ "Jenkins"`, false},
+		{`basescore >= 75`, true},
+		{`basescore > 75`, false},
+		{`basescore < -1`, false},
+		{`epss > 0.3`, true},
+		{`epss <= 0.3`, false},
+		// fields without operator
+		{`no_patch`, true},
+		{`kev`, true},
+		{`name`, false},
+		{`basescore`, true},
+		{`no_patch == false`, false},
+		// list fields: any element matches ...
+		{`products == "Jenkins LTS"`, true},
+		{`products ~ "LTS$"`, true},
+		{`products in ("OpenSSL", "Jenkins")`, true},
+		{`cves ~ "^CVE-2024-"`, true},
+		// ... or no element matches for != and !~
+		{`products != "Jenkins"`, false},
+		{`products != "OpenSSL"`, true},
+		{`products !~ "LTS$"`, false},
+		{`products !~ "^OpenSSL"`, true},
+		{`changed == "cves"`, true},
+		{`changed == "basescore"`, false},
+		// precedence: not > and > or
+		{`classification == "niedrig" and basescore > 0 or kev`, true},
+		{`classification == "niedrig" and (basescore > 0 or kev)`, false},
+		{`not kev or title`, true},
+		{`not (kev or title)`, false},
+		{`not not kev`, true},
+		// keywords and fields are case-insensitive
+		{`NOT Kev OR Title`, true},
+		{`Classification IN ("hoch") AND Event == "changed"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			e, err := ParseFilterExpression(tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.matches(&n); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		column int
+		message string
+	}{
+		{`foo == "x"`, 1, "unknown field 'foo'"},
+		{`title ~ "x`, 9, "unterminated string"},
+		{`kev & epss`, 5, "unexpected character '&'"},
+		{`title =~ "x"`, 7, "unknown operator '=~'"},
+		{`basescore >= "70"`, 14, "expected a number but got '70'"},
+		{`no_patch == yes`, 13, "expected true or false but got 'yes'"},
+		{`title == x`, 10, "expected a quoted text but got 'x'"},
+		{`title < 5`, 7, "operator '<' can only be used with numeric fields"},
+		{`kev ~ "x"`, 5, "operator '~' can only be used with text fields"},
+		{`title ~ "("`, 9, "invalid regular expression"},
+		{`classification in "hoch"`, 19, "expected '(' after 'in' but got 'hoch'"},
+		{`classification in ("a" "b")`, 24, "expected ',' or ')' but got 'b'"},
+		{`(kev`, 5, "expected ')' but got end of expression"},
+		{`basescore > 7 and`, 18, "expected a field but got end of expression"},
+		{`kev kev`, 5, "expected 'and', 'or' or end of expression but got 'kev'"},
+		{``, 1, "expected a field but got end of expression"},
+		// columns count characters, not bytes
+		{`title == "ä" or ü`, 17, "unknown field 'ü'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := ParseFilterExpression(tt.expression)
+			var exprErr ExpressionError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("got error %v, want an ExpressionError", err)
+			}
+			if exprErr.Column != tt.column || !strings.HasPrefix(exprErr.Message, tt.message) {
+				t.Errorf("got %q, want column %v: %v", err, tt.column, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+// RSS 2.0 or Atom feed, e.g. vendor security advisories
+
+type FeedSource struct {
+	Id string
+	Url string
+	Classification string
+}
+
+type rssDocument struct {
+	Channel struct {
+		Items []struct {
+			Title string `xml:"title"`
+			Link string `xml:"link"`
+			Guid string `xml:"guid"`
+			PubDate string `xml:"pubDate"`
+			Description string `xml:"description"`
+			Categories []string `xml:"category"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomDocument struct {
+	Entries []struct {
+		Id string `xml:"id"`
+		Title string `xml:"title"`
+		Updated string `xml:"updated"`
+		Published string `xml:"published"`
+		Summary string `xml:"summary"`
+		Content string `xml:"content"`
+		Links []struct {
+			Rel string `xml:"rel,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+var feedTimeFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+}
+
+func parseFeedTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, f := range feedTimeFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unknown time format '" + s + "'")
+}
+
+func (s FeedSource) sourceId() string {
+	return s.Id
+}
+
+func (s FeedSource) sourceName() string {
+	return "feed '" + s.Id + "'"
+}
+
+func (s FeedSource) getNotices(client *HttpClient, since time.Time) ([]WidNotice, time.Time, error) {
+	data, modified, err := client.forUrl(s.Url).getIfModified(s.Url)
+	if err != nil || !modified { return []WidNotice{}, since, err }
+	notices, err := s.parseFeed(data)
+	if err != nil { return []WidNotice{}, since, err }
+	return notices, latestPublished(notices, since), nil
+}
+
+func (s FeedSource) parseFeed(data []byte) ([]WidNotice, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil { return nil, err }
+	notices := []WidNotice{}
+	switch root.XMLName.Local {
+	case "rss":
+		doc := rssDocument{}
+		if err := xml.Unmarshal(data, &doc); err != nil { return nil, err }
+		for _, i := range doc.Channel.Items {
+			published, err := parseFeedTime(i.PubDate)
+			if err != nil {
+				logger.warn("Skipping feed item '" + i.Title + "' of source '" + s.Id + "'")
+				logger.warn(err)
+				continue
+			}
+			notices = append(notices, s.newNotice(firstNonEmpty(i.Guid, i.Link, i.Title), i.Title, published, i.Link, i.Categories, i.Description))
+		}
+	case "feed":
+		doc := atomDocument{}
+		if err := xml.Unmarshal(data, &doc); err != nil { return nil, err }
+		for _, e := range doc.Entries {
+			published, err := parseFeedTime(firstNonEmpty(e.Published, e.Updated))
+			if err != nil {
+				logger.warn("Skipping feed entry '" + e.Title + "' of source '" + s.Id + "'")
+				logger.warn(err)
+				continue
+			}
+			link := ""
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			categories := []string{}
+			for _, c := range e.Categories {
+				categories = append(categories, c.Term)
+			}
+			notices = append(notices, s.newNotice(firstNonEmpty(e.Id, link, e.Title), e.Title, published, link, categories, e.Summary + " " + e.Content))
+		}
+	default:
+		return nil, errors.New("'" + root.XMLName.Local + "' is neither a RSS nor an Atom feed")
+	}
+	return notices, nil
+}
+
+func (s FeedSource) newNotice(id string, title string, published time.Time, link string, categories []string, text string) WidNotice {
+	title = strings.TrimSpace(title)
+	return WidNotice{
+		Uuid: s.Id + ":" + id,
+		Name: id,
+		Title: title,
+		Published: published,
+		Classification: s.Classification,
+		Basescore: -1,
+		ProductNames: categories,
+		Cves: findCves(title, text),
+		ApiEndpointId: s.Id,
+		PortalUrl: link,
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -3,60 +3,319 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
+// StringList is a list of strings, a single string is accepted as well
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s == "" {
+			*l = StringList{}
+		} else {
+			*l = StringList{s}
+		}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
 type Filter struct {
+	// ids of named filters (see Config.Filters) that must match as well
+	Ref StringList `json:"ref"`
 	Any bool `json:"any"`
-	TitleContains string `json:"title_contains"`
+	TitleContains StringList `json:"title_contains"`
+	TitleRegex StringList `json:"title_regex"`
 	Classification string `json:"classification"`
 	MinBaseScore int `json:"min_basescore"`
 	Status string `json:"status"`
-	ProductsContain string `json:"products_contain"`
+	ProductsContain StringList `json:"products_contain"`
+	ProductsRegex StringList `json:"products_regex"`
+	// options for title_contains, title_regex, products_contain and products_regex
+	IgnoreCase bool `json:"ignore_case"`
+	WholeWord bool `json:"whole_word"` // only for title_contains and products_contain
 	NoPatch string `json:"no_patch"`
 	ApiEndpointId string `json:"api_endpoint"`
+	// cves
+	Cves StringList `json:"cves"`
+	CveRegex StringList `json:"cve_regex"`
+	CveYearMin int `json:"cve_year_min"`
+	CveYearMax int `json:"cve_year_max"`
+	CveWatchlist string `json:"cve_watchlist"` // file with one CVE id per line
+	// enrichment
+	Kev *bool `json:"kev"`
+	MinEpss float64 `json:"min_epss"`
+	// revisions
+	Event string `json:"event"` // "" or "new", "changed", "all"
+	ChangedField string `json:"changed_field"`
+	// boolean expression, see expression.go
+	Expression string `json:"expression"`
+	expression *FilterExpression
+	titleMatchers []*regexp.Regexp
+	productMatchers []*regexp.Regexp
+	cveMatchers []*regexp.Regexp
+	watchedCves []string
+	refs []*Filter
 }
 
-func (f Filter) filter(notices []WidNotice) []WidNotice {
-	filteredNotices := []WidNotice{}
-	for _, n := range notices {
-		matches := []bool{}
-		if f.Any {
-			matches = append(matches, true)
-		} else 
-		{
-			if f.TitleContains != "" {
-				matches = append(matches, strings.Contains(n.Title, f.TitleContains))
-			}
-			if f.Classification != "" {
-				matches = append(matches, f.Classification == n.Classification)
-			}
-			if f.MinBaseScore > 0 {
-				matches = append(matches, f.MinBaseScore <= n.Basescore)
-			}
-			if f.Status != "" {
-				matches = append(matches, f.Status == n.Status)
-			}
-			if f.ProductsContain != "" {
-				matches = append(matches, len(n.ProductNames) > 0)
-			}
-			if f.NoPatch != "" {
-				matches = append(matches, f.NoPatch == n.NoPatch)
-			}
-			if f.ApiEndpointId != "" {
-				matches = append(matches, f.ApiEndpointId == n.ApiEndpointId)
+// Named filters that can be referenced by id
+type FilterRegistry map[string]*Filter
+
+func (r FilterRegistry) checkCycles(id string, path []string) error {
+	if slices.Contains(path, id) {
+		return errors.New("filters reference each other: " + strings.Join(append(path, id), " -> "))
+	}
+	f, ok := r[id]
+	if !ok {
+		return nil // unknown ids are reported by compile
+	}
+	for _, ref := range f.Ref {
+		if err := r.checkCycles(ref, append(path, id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile parses the filter expression and text patterns and resolves
+// references to named filters, must be called before filter
+func (f *Filter) compile(registry FilterRegistry) error {
+	var err error
+	f.refs = []*Filter{}
+	for _, id := range f.Ref {
+		ref, ok := registry[id]
+		if !ok {
+			return errors.New("unknown filter id '" + id + "'")
+		}
+		f.refs = append(f.refs, ref)
+	}
+	f.titleMatchers, err = f.textMatchers(f.TitleContains, f.TitleRegex)
+	if err != nil { return err }
+	f.productMatchers, err = f.textMatchers(f.ProductsContain, f.ProductsRegex)
+	if err != nil { return err }
+	f.cveMatchers = []*regexp.Regexp{}
+	for _, p := range f.CveRegex {
+		r, err := regexp.Compile("(?i)" + p)
+		if err != nil { return err }
+		f.cveMatchers = append(f.cveMatchers, r)
+	}
+	if f.CveWatchlist != "" {
+		f.watchedCves, err = loadCveWatchlist(f.CveWatchlist)
+		if err != nil { return err }
+	}
+	if f.Expression == "" {
+		return nil
+	}
+	e, err := ParseFilterExpression(f.Expression)
+	if err != nil { return err }
+	f.expression = e
+	return nil
+}
+
+func (f Filter) textMatchers(texts []string, patterns []string) ([]*regexp.Regexp, error) {
+	flags := ""
+	if f.IgnoreCase {
+		flags = "(?i)"
+	}
+	matchers := []*regexp.Regexp{}
+	for _, t := range texts {
+		pattern := regexp.QuoteMeta(t)
+		if f.WholeWord {
+			// \b only knows ASCII
+			pattern = `(?:^|[^\p{L}\p{N}_])` + pattern + `(?:$|[^\p{L}\p{N}_])`
+		}
+		matchers = append(matchers, regexp.MustCompile(flags + pattern))
+	}
+	for _, p := range patterns {
+		r, err := regexp.Compile(flags + p)
+		if err != nil { return nil, err }
+		matchers = append(matchers, r)
+	}
+	return matchers, nil
+}
+
+// anyMatches returns true if any of the texts matches any of the matchers
+func anyMatches(matchers []*regexp.Regexp, texts ...string) bool {
+	for _, m := range matchers {
+		for _, t := range texts {
+			if m.MatchString(t) {
+				return true
 			}
 		}
-		allMatch := len(matches) > 0
-		for _, m := range matches {
-			if !m {
-				allMatch = false
-				break
+	}
+	return false
+}
+
+// event returns the event criteria, which is inherited from referenced filters
+func (f Filter) event() string {
+	if f.Event == "" {
+		for _, r := range f.refs {
+			if e := r.event(); e != "" {
+				return e
 			}
 		}
-		if allMatch {
+	}
+	return f.Event
+}
+
+func (f Filter) matchesEvent(n WidNotice) bool {
+	switch f.event() {
+	case "", "new":
+		return n.Event != "changed"
+	case "changed":
+		return n.Event == "changed"
+	}
+	return true
+}
+
+func (f Filter) filter(notices []WidNotice) []WidNotice {
+	filteredNotices := []WidNotice{}
+	for _, n := range notices {
+		if f.mismatch(&n) == "" {
 			filteredNotices = append(filteredNotices, n)
 		}
 	}
 	return filteredNotices
 }
+
+// mismatch returns why the notice doesn't match the filter, or "" if it matches
+func (f Filter) mismatch(n *WidNotice) string {
+	if !f.matchesEvent(*n) {
+		return "event is '" + n.Event + "'"
+	}
+	return f.criteriaMismatch(n)
+}
+
+// excludes is used for exclude filters, they apply to all events
+// unless 'event' is set
+func (f Filter) excludes(n *WidNotice) bool {
+	return (f.event() == "" || f.matchesEvent(*n)) && f.criteriaMismatch(n) == ""
+}
+
+// criteriaMismatch returns the first criteria that doesn't match, or "" if
+// all criteria match
+func (f Filter) criteriaMismatch(n *WidNotice) string {
+	criteria := 0
+	for i, r := range f.refs {
+		criteria++
+		if m := r.criteriaMismatch(n); m != "" {
+			return "ref '" + f.Ref[i] + "': " + m
+		}
+	}
+	if f.Any {
+		return ""
+	}
+	check := func(match bool) bool {
+		criteria++
+		return !match
+	}
+	if len(f.titleMatchers) > 0 && check(anyMatches(f.titleMatchers, n.Title)) {
+		return "title doesn't match"
+	}
+	if f.Classification != "" && check(f.Classification == n.Classification) {
+		return "classification is '" + n.Classification + "'"
+	}
+	if f.MinBaseScore > 0 && check(f.MinBaseScore <= n.Basescore) {
+		return fmt.Sprintf("basescore is %v", n.Basescore)
+	}
+	if f.Status != "" && check(f.Status == n.Status) {
+		return "status is '" + n.Status + "'"
+	}
+	if len(f.productMatchers) > 0 && check(anyMatches(f.productMatchers, n.ProductNames...)) {
+		return "no product matches"
+	}
+	if f.NoPatch != "" && check(f.NoPatch == n.NoPatch) {
+		return "no_patch is '" + n.NoPatch + "'"
+	}
+	if len(f.Cves) > 0 || len(f.cveMatchers) > 0 || f.CveYearMin > 0 || f.CveYearMax > 0 || f.CveWatchlist != "" {
+		cves := relevantCves(n)
+		if len(f.Cves) > 0 && check(slices.ContainsFunc(cves, func(c string) bool {
+			return slices.ContainsFunc(f.Cves, func(x string) bool { return strings.EqualFold(x, c) })
+		})) {
+			return "no CVE matches cves"
+		}
+		if len(f.cveMatchers) > 0 && check(anyMatches(f.cveMatchers, cves...)) {
+			return "no CVE matches cve_regex"
+		}
+		if (f.CveYearMin > 0 || f.CveYearMax > 0) && check(slices.ContainsFunc(cves, func(c string) bool {
+			year := cveYear(c)
+			return year > 0 && (f.CveYearMin == 0 || year >= f.CveYearMin) && (f.CveYearMax == 0 || year <= f.CveYearMax)
+		})) {
+			return "no CVE in the year range"
+		}
+		if f.CveWatchlist != "" && check(slices.ContainsFunc(cves, func(c string) bool {
+			return slices.Contains(f.watchedCves, strings.ToUpper(c))
+		})) {
+			return "no CVE is on the watchlist"
+		}
+	}
+	if f.ApiEndpointId != "" && check(f.ApiEndpointId == n.ApiEndpointId) {
+		return "api_endpoint is '" + n.ApiEndpointId + "'"
+	}
+	if f.Kev != nil && check(*f.Kev == n.Kev) {
+		return fmt.Sprintf("kev is %v", n.Kev)
+	}
+	if f.MinEpss > 0 && check(n.MaxEpss >= f.MinEpss) {
+		return fmt.Sprintf("epss is %v", n.MaxEpss)
+	}
+	if f.expression != nil && check(f.expression.matches(n)) {
+		return "expression doesn't match"
+	}
+	if f.ChangedField != "" && check(slices.ContainsFunc(n.Changes, func(c FieldChange) bool {
+		return c.Field == f.ChangedField
+	})) {
+		return f.ChangedField + " didn't change"
+	}
+	if criteria < 1 {
+		return "no criteria set"
+	}
+	return ""
+}
+
+// relevantCves returns the CVEs of a new notice, or the CVEs that were
+// added to a changed notice (all CVEs if the previous state is unknown)
+func relevantCves(n *WidNotice) []string {
+	if n.Event != "changed" || n.Changes == nil {
+		return n.Cves
+	}
+	for _, c := range n.Changes {
+		if c.Field == "cves" {
+			return c.Added
+		}
+	}
+	return []string{}
+}
+
+// cveYear returns the year of a CVE id like CVE-2024-12345, or 0
+func cveYear(cve string) int {
+	parts := strings.Split(cve, "-")
+	if len(parts) < 3 { return 0 }
+	year, err := strconv.Atoi(parts[1])
+	if err != nil { return 0 }
+	return year
+}
+
+// loadCveWatchlist reads a file with one CVE id per line, lines starting
+// with # are ignored
+func loadCveWatchlist(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	cves := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cves = append(cves, strings.ToUpper(strings.Fields(line)[0]))
+	}
+	return cves, nil
+}
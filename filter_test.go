@@ -0,0 +1,113 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterTextCriteria(t *testing.T) {
+	jenkins := WidNotice{
+		Title: "Jenkins Plugins: Mehrere Schwachstellen",
+		ProductNames: []string{"Jenkins LTS < 2.440.1", "Jenkins Plugin Git"},
+	}
+	// the product isn't mentioned in the title
+	openssl := WidNotice{
+		Title: "Mehrere Schwachstellen ermöglichen Codeausführung",
+		ProductNames: []string{"Open Source OpenSSL 3.0.12"},
+	}
+	umlauts := WidNotice{
+		Title: "Übersetzer und Büro-Software",
+		ProductNames: []string{"Übersetzer Pro", "Zoom-Client für Linux", "SAPphire"},
+	}
+	tests := []struct {
+		name string
+		filter string
+		notice WidNotice
+		want bool
+	}{
+		// products_contain is matched against the product names, not the title
+		{"products_contain, product name", `{"products_contain": "OpenSSL"}`, openssl, true},
+		{"products_contain, not in title", `{"title_contains": "OpenSSL"}`, openssl, false},
+		{"products_contain, only in title", `{"products_contain": "Schwachstellen"}`, jenkins, false},
+		{"products_contain, substring", `{"products_contain": "Plugin Git"}`, jenkins, true},
+		{"products_contain, no product", `{"products_contain": "OpenSSL"}`, WidNotice{Title: "OpenSSL"}, false},
+		// ignore_case
+		{"case-sensitive by default", `{"products_contain": "jenkins"}`, jenkins, false},
+		{"ignore_case", `{"products_contain": "jenkins", "ignore_case": true}`, jenkins, true},
+		{"ignore_case, title", `{"title_contains": "JENKINS PLUGINS", "ignore_case": true}`, jenkins, true},
+		{"ignore_case, umlauts", `{"products_contain": "ÜBERSETZER", "ignore_case": true}`, umlauts, true},
+		// whole_word
+		{"whole_word", `{"products_contain": "Jenkins", "whole_word": true}`, jenkins, true},
+		{"whole_word, part of a word", `{"products_contain": "SAP", "whole_word": true}`, umlauts, false},
+		{"whole_word, part of a word without whole_word", `{"products_contain": "SAP"}`, umlauts, true},
+		{"whole_word, hyphen", `{"products_contain": "Zoom", "whole_word": true}`, umlauts, true},
+		{"whole_word, preceded by umlaut", `{"products_contain": "bersetzer", "whole_word": true}`, umlauts, false},
+		{"whole_word, followed by umlaut", `{"title_contains": "B", "whole_word": true}`, umlauts, false},
+		{"whole_word, starting with umlaut", `{"title_contains": "Übersetzer", "whole_word": true}`, umlauts, true},
+		{"whole_word, ending with umlaut", `{"products_contain": "für", "whole_word": true}`, umlauts, true},
+		{"whole_word and ignore_case", `{"products_contain": "übersetzer", "whole_word": true, "ignore_case": true}`, umlauts, true},
+		// regular expressions
+		{"title_regex", `{"title_regex": "^Jenkins( Plugins)?:"}`, jenkins, true},
+		{"title_regex, no match", `{"title_regex": "^Plugins"}`, jenkins, false},
+		{"products_regex", `{"products_regex": "OpenSSL 3\\.0\\.\\d+"}`, openssl, true},
+		{"products_regex, no match", `{"products_regex": "OpenSSL 1\\."}`, openssl, false},
+		{"products_regex, ignore_case", `{"products_regex": "^open source", "ignore_case": true}`, openssl, true},
+		{"products_regex, whole_word is ignored", `{"products_regex": "SAP", "whole_word": true}`, umlauts, true},
+		// list-valued criteria: any of the texts matches
+		{"list, second matches", `{"products_contain": ["OpenSSH", "OpenSSL"]}`, openssl, true},
+		{"list, none matches", `{"products_contain": ["OpenSSH", "GnuTLS"]}`, openssl, false},
+		{"list, title", `{"title_contains": ["Apache", "Jenkins"]}`, jenkins, true},
+		{"list, regex", `{"title_regex": ["^Apache", "Schwachstellen$"]}`, jenkins, true},
+		{"list and regex", `{"products_contain": "GnuTLS", "products_regex": "OpenSSL"}`, openssl, true},
+		{"empty list", `{"products_contain": [], "title_contains": "Jenkins"}`, jenkins, true},
+		// all criteria must match
+		{"title and products", `{"title_contains": "Jenkins", "products_contain": "OpenSSL"}`, jenkins, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Filter{}
+			if err := json.Unmarshal([]byte(tt.filter), &f); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.compile(FilterRegistry{}); err != nil {
+				t.Fatal(err)
+			}
+			n := tt.notice
+			if m := f.criteriaMismatch(&n); (m == "") != tt.want {
+				t.Errorf("got mismatch %q, want match = %v", m, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCompileErrors(t *testing.T) {
+	tests := []string{
+		`{"title_regex": "("}`,
+		`{"products_regex": ["ok", "[a-"]}`,
+		`{"ref": "unknown"}`,
+		`{"expression": "title ~"}`,
+	}
+	for _, filter := range tests {
+		t.Run(filter, func(t *testing.T) {
+			f := Filter{}
+			if err := json.Unmarshal([]byte(filter), &f); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.compile(FilterRegistry{}); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestFilterNoCriteria(t *testing.T) {
+	f := Filter{}
+	if err := f.compile(FilterRegistry{}); err != nil {
+		t.Fatal(err)
+	}
+	if m := f.criteriaMismatch(&WidNotice{Title: "x"}); m != "no criteria set" {
+		t.Errorf("got mismatch %q, want 'no criteria set'", m)
+	}
+}
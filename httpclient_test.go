@@ -0,0 +1,47 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHttpClientFileUrls(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(file, []byte("secret"), 0600); err != nil { t.Fatal(err) }
+	fileUrl := "file://" + file
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, fileUrl, http.StatusFound)
+	}))
+	defer server.Close()
+	client, err := NewHttpClient(HttpSettings{})
+	if err != nil { t.Fatal(err) }
+	tests := []struct {
+		name string
+		client *HttpClient
+		url string
+		ok bool
+	}{
+		{"file url", client, fileUrl, false},
+		{"file url of a remote source", client.forUrl(server.URL), fileUrl, false},
+		{"file url of a file source", client.forUrl("file:///srv/csaf/provider-metadata.json"), fileUrl, true},
+		{"redirect to a file", client, server.URL, false},
+		{"redirect to a file for a file source", client.forUrl("FILE:///srv/feed.xml"), server.URL, false},
+		{"other scheme", client, "gopher://example.org/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := tt.client.get(tt.url)
+			if tt.ok && (err != nil || string(body) != "secret") {
+				t.Errorf("got %q, %v, want the file", body, err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("got %q, want an error", body)
+			}
+		})
+	}
+}
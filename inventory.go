@@ -0,0 +1,212 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Asset inventories: CycloneDX or SPDX JSON SBOMs, or CSV files with the
+// columns product, version and (optionally) cpe
+
+type Asset struct {
+	Name string
+	Version string
+	Cpe string
+	tokens []string // normalized name
+}
+
+func (a Asset) String() string {
+	if a.Version != "" {
+		return a.Name + " " + a.Version
+	}
+	return a.Name
+}
+
+type cycloneDxComponent struct {
+	Group string `json:"group"`
+	Name string `json:"name"`
+	Version string `json:"version"`
+	Cpe string `json:"cpe"`
+	Components []cycloneDxComponent `json:"components"`
+}
+
+type sbomDocument struct {
+	// CycloneDX
+	BomFormat string `json:"bomFormat"`
+	Metadata struct {
+		Component *cycloneDxComponent `json:"component"`
+	} `json:"metadata"`
+	Components []cycloneDxComponent `json:"components"`
+	// SPDX
+	SpdxVersion string `json:"spdxVersion"`
+	Packages []struct {
+		Name string `json:"name"`
+		VersionInfo string `json:"versionInfo"`
+		ExternalRefs []struct {
+			ReferenceType string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+func loadInventory(path string) ([]Asset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	assets := []Asset{}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil { return nil, err }
+		for i, r := range records {
+			if len(r) < 1 || strings.TrimSpace(r[0]) == "" {
+				continue
+			}
+			if i == 0 && strings.EqualFold(strings.TrimSpace(r[0]), "product") {
+				continue // header
+			}
+			a := Asset{Name: strings.TrimSpace(r[0])}
+			if len(r) > 1 { a.Version = strings.TrimSpace(r[1]) }
+			if len(r) > 2 { a.Cpe = strings.TrimSpace(r[2]) }
+			assets = append(assets, a)
+		}
+	} else {
+		doc := sbomDocument{}
+		if err = json.Unmarshal(data, &doc); err != nil { return nil, err }
+		if doc.BomFormat == "CycloneDX" {
+			var walk func(components []cycloneDxComponent)
+			walk = func(components []cycloneDxComponent) {
+				for _, c := range components {
+					name := c.Name
+					if c.Group != "" && !strings.Contains(strings.ToLower(c.Name), strings.ToLower(c.Group)) {
+						name = c.Group + " " + c.Name
+					}
+					assets = append(assets, Asset{Name: name, Version: c.Version, Cpe: c.Cpe})
+					walk(c.Components)
+				}
+			}
+			if doc.Metadata.Component != nil {
+				walk([]cycloneDxComponent{*doc.Metadata.Component})
+			}
+			walk(doc.Components)
+		} else if doc.SpdxVersion != "" {
+			for _, p := range doc.Packages {
+				a := Asset{Name: p.Name, Version: p.VersionInfo}
+				for _, r := range p.ExternalRefs {
+					if r.ReferenceType == "cpe23Type" || r.ReferenceType == "cpe22Type" {
+						a.Cpe = r.ReferenceLocator
+					}
+				}
+				assets = append(assets, a)
+			}
+		} else {
+			return nil, errors.New(path + " is neither a CycloneDX nor a SPDX document")
+		}
+	}
+	for i := range assets {
+		assets[i].tokens = normalizeProductName(assets[i].Name)
+	}
+	return assets, nil
+}
+
+// words that don't help to identify a product
+var productNameNoise = []string{
+	"the", "for", "and", "of", "inc", "gmbh", "ag", "corp", "corporation",
+	"ltd", "llc", "project", "foundation", "software", "edition", "version",
+}
+
+// common package names that differ from the product names used in advisories
+var productNameAliases = map[string]string{
+	"httpd": "apache http server",
+	"apache2": "apache http server",
+	"nodejs": "node js",
+	"postgres": "postgresql",
+	"mysql-server": "mysql",
+	"chromium-browser": "chromium",
+	"firefox-esr": "mozilla firefox esr",
+	"thunderbird": "mozilla thunderbird",
+}
+
+// normalizeProductName returns the significant lower case words of a
+// product name, without version numbers
+func normalizeProductName(name string) []string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := productNameAliases[name]; ok {
+		name = alias
+	}
+	tokens := []string{}
+	for _, t := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		isVersion := strings.IndexFunc(t, unicode.IsLetter) < 0 || (t[0] == 'v' && len(t) > 1 && unicode.IsDigit(rune(t[1])))
+		if isVersion || slices.Contains(productNameNoise, t) || slices.Contains(tokens, t) {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+func tokensSubset(a []string, b []string) bool {
+	for _, t := range a {
+		if !slices.Contains(b, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// cpeVendorProduct returns "vendor:product" of a CPE 2.2 or 2.3 name
+func cpeVendorProduct(cpe string) string {
+	cpe = strings.ToLower(cpe)
+	var parts []string
+	if strings.HasPrefix(cpe, "cpe:2.3:") {
+		parts = strings.Split(cpe, ":")[3:]
+	} else if strings.HasPrefix(cpe, "cpe:/") {
+		parts = strings.Split(cpe, ":")[2:]
+	}
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + ":" + parts[1]
+}
+
+// matchAssets returns the assets that are affected by the notice
+func matchAssets(n *WidNotice, assets []Asset) []Asset {
+	matched := []Asset{}
+	products := [][]string{}
+	for _, p := range n.ProductNames {
+		products = append(products, normalizeProductName(p))
+	}
+	cpes := []string{}
+	for _, c := range n.Cpes {
+		if vp := cpeVendorProduct(c); vp != "" {
+			cpes = append(cpes, vp)
+		}
+	}
+	for _, a := range assets {
+		match := false
+		if vp := cpeVendorProduct(a.Cpe); vp != "" && slices.Contains(cpes, vp) {
+			match = true
+		}
+		for _, p := range products {
+			if match { break }
+			if len(a.tokens) > 0 && len(p) > 0 && (tokensSubset(a.tokens, p) || tokensSubset(p, a.tokens)) {
+				match = true
+			}
+		}
+		if match {
+			matched = append(matched, a)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generic JSON API, the notice fields are mapped using paths like "data.0.title"
+
+var jsonSourceFields = []string{
+	"uuid", "name", "title", "published", "classification", "basescore",
+	"status", "product_names", "cves", "no_patch", "url",
+}
+
+type JsonSource struct {
+	Id string
+	Url string
+	Classification string
+	ItemsPath string
+	Fields map[string]string // notice field : path
+	TimeFormat string
+	BasescoreFactor float64
+}
+
+func (s JsonSource) sourceId() string {
+	return s.Id
+}
+
+func (s JsonSource) sourceName() string {
+	return "JSON source '" + s.Id + "'"
+}
+
+func (s JsonSource) getNotices(client *HttpClient, since time.Time) ([]WidNotice, time.Time, error) {
+	data, modified, err := client.forUrl(s.Url).getIfModified(s.Url)
+	if err != nil || !modified { return []WidNotice{}, since, err }
+	var decodedData any
+	if err = json.Unmarshal(data, &decodedData); err != nil {
+		return []WidNotice{}, since, err
+	}
+	notices, err := s.parseItems(decodedData)
+	if err != nil { return []WidNotice{}, since, err }
+	return notices, latestPublished(notices, since), nil
+}
+
+func (s JsonSource) parseItems(data any) ([]WidNotice, error) {
+	items, ok := jsonPathLookup(data, s.ItemsPath)
+	if !ok {
+		return nil, errors.New("items path '" + s.ItemsPath + "' not found")
+	}
+	itemList, ok := items.([]any)
+	if !ok {
+		return nil, errors.New("items path '" + s.ItemsPath + "' is not a list")
+	}
+	notices := []WidNotice{}
+	for i, item := range itemList {
+		n, err := s.parseItem(item)
+		if err != nil {
+			logger.warn(fmt.Sprintf("Skipping item %v of source '%v'", i, s.Id))
+			logger.warn(err)
+			continue
+		}
+		notices = append(notices, n)
+	}
+	return notices, nil
+}
+
+func (s JsonSource) parseItem(item any) (WidNotice, error) {
+	str := func(field string) string {
+		v, ok := jsonPathLookup(item, s.Fields[field])
+		if !ok || v == nil || s.Fields[field] == "" { return "" }
+		return fmt.Sprint(v)
+	}
+	strList := func(field string) []string {
+		l := []string{}
+		v, ok := jsonPathLookup(item, s.Fields[field])
+		if !ok || s.Fields[field] == "" { return l }
+		switch v := v.(type) {
+		case []any:
+			for _, x := range v {
+				l = append(l, fmt.Sprint(x))
+			}
+		case string:
+			l = append(l, v)
+		}
+		return l
+	}
+	n := WidNotice{
+		Name: str("name"),
+		Title: str("title"),
+		Classification: strings.ToLower(str("classification")),
+		Basescore: -1,
+		Status: str("status"),
+		ProductNames: strList("product_names"),
+		Cves: strList("cves"),
+		NoPatch: str("no_patch"),
+		ApiEndpointId: s.Id,
+		PortalUrl: str("url"),
+	}
+	published, err := time.Parse(s.TimeFormat, str("published"))
+	if err != nil { return n, err }
+	n.Published = published
+	if n.Title == "" {
+		return n, errors.New("item has no title")
+	}
+	n.Uuid = s.Id + ":" + firstNonEmpty(str("uuid"), n.Name, n.Title)
+	if n.Name == "" {
+		n.Name = n.Title
+	}
+	if n.Classification == "" {
+		n.Classification = s.Classification
+	}
+	if v := str("basescore"); v != "" {
+		score, err := strconv.ParseFloat(v, 64)
+		if err != nil { return n, err }
+		n.Basescore = int(score * s.BasescoreFactor)
+	}
+	return n, nil
+}
+
+// jsonPathLookup resolves a dot-separated path like "data.items.0.title",
+// an empty path returns the value itself
+func jsonPathLookup(v any, path string) (any, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = x[key]; !ok { return nil, false }
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(x) { return nil, false }
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -3,31 +3,65 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"mime"
+	"mime/multipart"
 	"mime/quotedprintable"
+	"net/textproto"
 	"net/mail"
 	"net/smtp"
+	"slices"
 	"strings"
 )
 
 type MailContent struct {
 	Subject string
 	Body string
+	Html string // optional
+	Markdown string // optional, the source of Body and Html
+	// RFC 8058 one-click unsubscribe url, set per recipient
+	ListUnsubscribe string
+}
+
+// quotedPrintable formats text using Quoted-Printable Encoding from RFC2045
+func quotedPrintable(text string) string {
+	var encoded strings.Builder
+	w := quotedprintable.NewWriter(&encoded)
+	w.Write([]byte(text))
+	w.Close()
+	return encoded.String()
 }
 
 func (c MailContent) serializeValidMail(from string, to string) []byte {
 	// format subject using Q Encoding from RFC2047
 	subjectEncoded := mime.QEncoding.Encode("utf-8", c.Subject)
-	// format body using Quoted-Printable Encoding from RFC2045
-	var bodyEncoded strings.Builder
-	bew := quotedprintable.NewWriter(&bodyEncoded)
-	bew.Write([]byte(c.Body))
-	bew.Close()
+	headers := ""
+	if c.ListUnsubscribe != "" {
+		headers = fmt.Sprintf("List-Unsubscribe: <%v>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n", c.ListUnsubscribe)
+	}
+	if c.Html != "" {
+		// text and html as alternatives
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		for _, part := range [][2]string{{"text/plain", c.Body}, {"text/html", c.Html}} {
+			pw, _ := w.CreatePart(textproto.MIMEHeader{
+				"Content-Type": {part[0] + "; charset=\"utf-8\""},
+				"Content-Transfer-Encoding": {"Quoted-Printable"},
+			})
+			pw.Write([]byte(quotedPrintable(part[1])))
+		}
+		w.Close()
+		return fmt.Appendf(nil,
+			"MIME-Version: 1.0\r\nContent-Type: multipart/alternative; boundary=\"%v\"\r\nFrom: %v\r\nTo: %v\r\nSubject: %v\r\n%v\r\n%v",
+			w.Boundary(), from, to, subjectEncoded, headers, body.String(),
+		)
+	}
 	// glue it all together
 	data := fmt.Appendf(nil, 
-		"Content-Type: text/plain; charset=\"utf-8\"\r\nContent-Transfer-Encoding: Quoted-Printable\r\nFrom: %v\r\nTo: %v\r\nSubject: %v\r\n\r\n%v",
-		from, to, subjectEncoded, bodyEncoded.String(),
+		"Content-Type: text/plain; charset=\"utf-8\"\r\nContent-Transfer-Encoding: Quoted-Printable\r\nFrom: %v\r\nTo: %v\r\nSubject: %v\r\n%v\r\n%v",
+		from, to, subjectEncoded, headers, quotedPrintable(c.Body),
 	)
 	return data
 }
@@ -35,8 +69,88 @@ func (c MailContent) serializeValidMail(from string, to string) []byte {
 type NotifyList struct {
 	Name string `json:"name"`
 	Recipients []string `json:"recipients"`
-	// Must be a configured filter id
+	// Filters can reference named filters by id
 	Filter []Filter `json:"filter"`
+	// Notices matching any of these filters are left out
+	Exclude []Filter `json:"exclude"`
+	// SBOMs (CycloneDX, SPDX) or CSV files, if set only notices affecting
+	// these assets are sent
+	Inventory []string `json:"inventory"`
+	// nil = notices are sent immediately
+	Delivery *DeliverySettings `json:"delivery"`
+	// overrides the global template, nil = global template
+	Template *MailTemplateConfig `json:"template"`
+	Language string `json:"language"` // "" = language of the global template
+
+	assets []Asset
+}
+
+func (l *NotifyList) loadInventory() error {
+	l.assets = []Asset{}
+	for _, path := range l.Inventory {
+		a, err := loadInventory(path)
+		if err != nil { return err }
+		l.assets = append(l.assets, a...)
+	}
+	logger.debug(fmt.Sprintf("Loaded %v assets for list '%v'", len(l.assets), l.Name))
+	return nil
+}
+
+type listMatch struct {
+	notice WidNotice // with MatchedAssets
+	filter int // index of the first matching filter, -1 = none
+	included bool
+	reason string
+}
+
+// matchNotice checks if a notice matches any filter of the list
+// and none of its exclude filters
+func (l NotifyList) matchNotice(n WidNotice) listMatch {
+	m := listMatch{notice: n, filter: -1}
+	reasons := []string{}
+	for i, f := range l.Filter {
+		r := f.mismatch(&n)
+		if r == "" {
+			m.filter = i
+			break
+		}
+		reasons = append(reasons, fmt.Sprintf("filter %v: %v", i, r))
+	}
+	if m.filter < 0 {
+		m.reason = strings.Join(reasons, ", ")
+		return m
+	}
+	for i, e := range l.Exclude {
+		if e.excludes(&n) {
+			m.reason = fmt.Sprintf("excluded by exclude filter %v", i)
+			return m
+		}
+	}
+	if len(l.Inventory) > 0 {
+		m.notice.MatchedAssets = matchAssets(&n, l.assets)
+		if len(m.notice.MatchedAssets) < 1 {
+			m.reason = "doesn't affect any asset"
+			return m
+		}
+	}
+	m.included = true
+	m.reason = fmt.Sprintf("matches filter %v", m.filter)
+	return m
+}
+
+// filterNotices returns the notices matching any filter of the list
+// and none of its exclude filters
+func (l NotifyList) filterNotices(notices []WidNotice) []WidNotice {
+	filteredNotices := []WidNotice{}
+	for _, n := range notices {
+		m := l.matchNotice(n)
+		if m.included {
+			filteredNotices = append(filteredNotices, m.notice)
+		} else if m.filter >= 0 {
+			logger.debug(fmt.Sprintf("Notice %v left out from list '%v': %v", n.Name, l.Name, m.reason))
+		}
+	}
+	return filteredNotices
 }
 
 type SmtpSettings struct {
@@ -45,16 +159,22 @@ type SmtpSettings struct {
 	ServerPort int `json:"port"`
 	User string `json:"user"`
 	Password string `json:"password"`
+	// alternatively, see secrets.go
+	PasswordFile string `json:"password_file"`
+	PasswordCommand string `json:"password_command"`
 }
 
-func sendNotices(recipient string, notices []*WidNotice, template MailTemplate, auth smtp.Auth, smtpConfig SmtpSettings, mailContentCache *map[string]*MailContent) error {
-	logger.debug("Generating and sending mails for recipient " + recipient + " ...")
+// generateMails creates the mail contents for the notices, or takes them
+// from the cache. Mails that couldn't be created are nil.
+func generateMails(notices []*WidNotice, templateFor func(*WidNotice) MailTemplate, mailContentCache *map[string]*MailContent) []*MailContent {
 	cacheHits := 0
 	cacheMisses := 0
 	mails := []*MailContent{}
 	for _, n := range notices {
 		var mc *MailContent
-		cacheResult := (*mailContentCache)[n.Uuid]
+		template := templateFor(n)
+		cacheKey := template.Name + "|" + n.cacheKey()
+		cacheResult := (*mailContentCache)[cacheKey]
 		if cacheResult != nil {
 			cacheHits++
 			mc = cacheResult
@@ -62,25 +182,100 @@ func sendNotices(recipient string, notices []*WidNotice, template MailTemplate,
 			cacheMisses++
 			mc_, err := template.generate(TemplateData{n, Version})
 			if err != nil {
-				logger.error("Could not create mail from template")
+				logger.error("Could not create mail for notice " + n.Name + " from template")
 				logger.error(err)
 			} else {
 				mc = &mc_
 				// add to cache
-				(*mailContentCache)[n.Uuid] = mc
+				(*mailContentCache)[cacheKey] = mc
 			}
 		}
 		mails = append(mails, mc)
 	}
 	logger.debug(fmt.Sprintf("%v mail cache hits, %v misses", cacheHits, cacheMisses))
-	err := sendMails(
-		smtpConfig,
-		auth,
-		recipient,
-		mails,
-	)
-	if err != nil { return err }
-	logger.debug("Successfully sent all mails to " + recipient)
+	return mails
+}
+
+// digestMail combines multiple mails into one
+func digestMail(mails []*MailContent, language string) *MailContent {
+	subject := translate(language, "%v new security notices", len(mails))
+	if !slices.ContainsFunc(mails, func(mc *MailContent) bool { return mc.Markdown == "" }) {
+		// Markdown templates, the digest has a HTML part too
+		parts := []string{}
+		for _, mc := range mails {
+			parts = append(parts, "# " + markdownSpecialChars.ReplaceAllString(mc.Subject, "\\$1") + "\n\n" + strings.TrimSpace(mc.Markdown))
+		}
+		md := strings.Join(parts, "\n\n---\n\n") + "\n"
+		return &MailContent{Subject: subject, Body: markdownToText(md), Html: markdownToHtml(md), Markdown: md}
+	}
+	parts := []string{}
+	for _, mc := range mails {
+		parts = append(parts, mc.Subject + "\n" + strings.Repeat("=", min(len([]rune(mc.Subject)), 72)) + "\n\n" + strings.TrimSpace(mc.Body))
+	}
+	return &MailContent{
+		Subject: subject,
+		Body: strings.Join(parts, "\n\n\n") + "\n",
+	}
+}
+
+// withUnsubscribe returns copies of the mails with a List-Unsubscribe url
+// for the lists the notices of each mail were selected from
+func withUnsubscribe(mails []*MailContent, lists [][]string, address string, portal PortalSettings) []*MailContent {
+	if !portal.enabled() { return mails }
+	result := []*MailContent{}
+	for i, mc := range mails {
+		if mc != nil {
+			c := *mc
+			c.ListUnsubscribe = unsubscribeUrl(portal, address, lists[i])
+			mc = &c
+		}
+		result = append(result, mc)
+	}
+	return result
+}
+
+func sendNotices(recipient Recipient, notices []*WidNotice, templates MailTemplates, auth smtp.Auth, smtpConfig SmtpSettings, portal PortalSettings, client *HttpClient, mailContentCache *map[string]*MailContent) error {
+	logger.debug("Generating and sending mails for recipient " + recipient.Address + " ...")
+	mails := []*MailContent{}
+	lists := [][]string{}
+	templateFor := func(n *WidNotice) MailTemplate { return templates.forNotice(n, recipient.Language) }
+	digest := recipient.Digest && len(notices) > 1
+	language := templates.digestLanguage(recipient.Language)
+	if digest {
+		// all parts in the same language
+		templateFor = func(n *WidNotice) MailTemplate { return templates.inLanguage(n, language) }
+	}
+	for i, mc := range generateMails(notices, templateFor, mailContentCache) {
+		// leave out notices with template errors
+		if mc != nil {
+			mails = append(mails, mc)
+			lists = append(lists, notices[i].Lists)
+		}
+	}
+	if len(mails) < 1 {
+		return errors.New("couldn't create any mail for recipient " + recipient.Address)
+	}
+	if digest && len(mails) > 1 {
+		mails = []*MailContent{digestMail(mails, language)}
+		allLists := []string{}
+		for _, n := range notices {
+			for _, l := range n.Lists {
+				if !slices.Contains(allLists, l) { allLists = append(allLists, l) }
+			}
+		}
+		lists = [][]string{allLists}
+	}
+	for _, c := range recipient.channels() {
+		var err error
+		switch c.Type {
+		case "mail":
+			err = sendMails(smtpConfig, auth, recipient.Address, withUnsubscribe(mails, lists, recipient.Address, portal))
+		case "webhook":
+			err = sendWebhook(client, c, mails)
+		}
+		if err != nil { return err }
+	}
+	logger.debug("Successfully sent all mails to " + recipient.Address)
 	return nil
 }
 
@@ -4,9 +4,11 @@ package main
 
 import (
 	"fmt"
-	"net/smtp"
+	"maps"
 	"os"
+	"os/signal"
 	"slices"
+	"syscall"
 	"time"
 )
 
@@ -19,72 +21,123 @@ func showVersion() {
 }
 
 func showHelp() {
-	fmt.Printf("Usage: %v <configfile>\n\nIf the config file doesn't exist, an incomplete \n" +
-			   "configuration with default values is created.\n\n",
+	fmt.Printf("Usage: %v [--once] <configfile> [explain [noticesfile] | preview [noticesfile|sources [outdir]]]\n\nIf the config file doesn't exist, an incomplete \n" +
+			   "configuration with default values is created.\n\n" +
+			   "  --once    query the sources and send notifications only once, then exit\n\n" +
+			   "Commands:\n" +
+			   "  explain   show which notices match the filters of each list and why,\n" +
+			   "            without sending mails. The notices are read from noticesfile\n" +
+			   "            (JSON) or queried from the sources.\n" +
+			   "  preview   render the mail templates against sample notices, the\n" +
+			   "            notices in noticesfile or, with 'sources', the notices of\n" +
+			   "            the sources and print the mails, or write them to outdir.\n" +
+			   "            Exits with status 1 if a template has errors.\n\n",
 			   executableName)
 	showVersion()
 }
 
+func queryNotices(s Source, client *HttpClient, since time.Time) ([]WidNotice, time.Time, error) {
+	n, t, err := s.getNotices(client, since)
+	if err != nil {
+		// retry (once)
+		logger.warn("Couldn't query notices from " + s.sourceName() + ". Retrying ...")
+		logger.warn(err)
+		n, t, err = s.getNotices(client, since)
+	}
+	if err != nil {
+		// ok then...
+		logger.error("Couldn't query notices from " + s.sourceName())
+		logger.error(err)
+	}
+	return n, t, err
+}
+
 func main() {
 	// get cli arguments
 	args := os.Args
 	executableName = args[0]
-	if len(args) < 2 {
-		showHelp()
-		os.Exit(1)
-	}
-	for _, arg := range args {
+	once := false
+	positionalArgs := []string{}
+	for _, arg := range args[1:] {
 		if arg == "-h" || arg == "--help" {
 			showHelp()
 			os.Exit(0)
 		} else if arg == "--version" {
 			showVersion()
 			os.Exit(0)
+		} else if arg == "--once" {
+			once = true
+		} else {
+			positionalArgs = append(positionalArgs, arg)
+		}
+	}
+	if len(positionalArgs) < 1 {
+		showHelp()
+		os.Exit(1)
+	}
+	configFilePath := positionalArgs[0]
+	command := ""
+	if len(positionalArgs) > 1 {
+		command = positionalArgs[1]
+		if command != "explain" && command != "preview" {
+			showHelp()
+			os.Exit(1)
 		}
 	}
-	configFilePath := args[1]
 	// create logger
 	logger = NewLogger(2)
 	// init
 	logger.info("Initializing ...")
 	defer logger.info("Exiting ...")
 	// open & check config
-	config := NewDataStore(
-		configFilePath,
-		NewConfig(),
-		true,
-		0600,
-	).data.(Config)
+	config, err := loadConfig(configFilePath)
+	if err != nil {
+		logger.error("Couldn't load configuration file " + configFilePath)
+		logger.error(err)
+		os.Exit(1)
+	}
 	logger.LogLevel = config.LogLevel
 	logger.debug("Checking configuration file ...")
-	checkConfig(config)
-	// create mail template from mail template config
-	logger.debug("Parsing mail template ...")
-	if config.Template.SubjectTemplate == "" {
-		logger.debug("Using default template for mail subject")
-		config.Template.SubjectTemplate = DEFAULT_SUBJECT_TEMPLATE
+	filterRegistry := checkConfig(&config)
+	if command == "preview" {
+		// doesn't send mails or touch the data file
+		noticesFilePath := ""
+		outDir := ""
+		if len(positionalArgs) > 2 {
+			noticesFilePath = positionalArgs[2]
+		}
+		if len(positionalArgs) > 3 {
+			outDir = positionalArgs[3]
+		}
+		if !preview(config, noticesFilePath, outDir) {
+			os.Exit(1)
+		}
+		return
+	}
+	// mail templates, mail authentication, http client, sources, ...
+	rt, err := NewRuntime(config, filterRegistry, nil)
+	if err != nil {
+		logger.error(err)
+		os.Exit(1)
 	}
-	if config.Template.BodyTemplate == "" {
-		logger.debug("Using default template for mail body")
-		config.Template.BodyTemplate = DEFAULT_BODY_TEMPLATE
+	// subscriptions made in the portal
+	var subscriptions *SubscriptionStore
+	if config.Portal.enabled() {
+		subscriptions, err = NewSubscriptionStore(config.Portal.SubscriptionsFile, filterRegistry)
+		if err != nil {
+			logger.error("Couldn't load subscriptions from " + config.Portal.SubscriptionsFile)
+			logger.error(err)
+			os.Exit(1)
+		}
 	}
-	mailTemplate := NewTemplateFromTemplateConfig(config.Template)
-	// mail authentication from config
-	mailAuth := smtp.PlainAuth(
-		"",
-		config.SmtpConfiguration.User,
-		config.SmtpConfiguration.Password,
-		config.SmtpConfiguration.ServerHost,
-	)
-	// filter out disabled api endpoints
-	enabledApiEndpoints := []ApiEndpoint{}
-	for _, a := range apiEndpoints {
-		for _, b := range config.EnabledApiEndpoints {
-			if a.Id == b {
-				logger.debug("Endpoint '" + b + "' is enabled")
-				enabledApiEndpoints = append(enabledApiEndpoints, a)
-			}
+	if command == "explain" {
+		// doesn't touch the data file
+		noticesFilePath := ""
+		if len(positionalArgs) > 2 {
+			noticesFilePath = positionalArgs[2]
 		}
+		explain(config, rt.sources, rt.httpClient, rt.enricher, subscriptions, noticesFilePath)
+		return
 	}
 	// open data file
 	persistent := NewDataStore(
@@ -92,52 +145,115 @@ func main() {
 		NewPersistentData(config),
 		false,
 		0640)
+	initLastPublished := func(config Config) {
+		for _, s := range config.Sources {
+			// sources that were added after the data file was created
+			if _, ok := persistent.data.(PersistentData).LastPublished[s.Id]; !ok {
+				persistent.data.(PersistentData).LastPublished[s.Id] = time.Now().Add(-time.Hour * 24) // a day ago
+			}
+		}
+	}
+	initLastPublished(config)
+	var portal *Portal
+	if config.Portal.enabled() {
+		portal = NewPortal(config, subscriptions, rt.mailAuth)
+		portal.serve()
+	}
+	// reload the configuration on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	if config.ReloadOnChange {
+		go watchConfigFile(configFilePath, reload)
+	}
 	// main loop
 	logger.debug("Entering main loop ...")
 	for {
-		t1 := time.Now().UnixMilli()
+		t1 := time.Now()
 		newNotices := []WidNotice{}
 		lastPublished := map[string]time.Time{} // endpoint id : last published timestamp
+		fingerprints := map[string]NoticeFingerprint{} // notice uuid : current fingerprint
+		quietSources := []string{} // sources that answered without notices, e.g. not modified
 		cache := map[string]*MailContent{}      // cache generated emails for reuse
-		for _, a := range enabledApiEndpoints {
-			logger.info("Querying endpoint '" + a.Id + "' for new notices ...")
-			n, t, err := a.getNotices(persistent.data.(PersistentData).LastPublished[a.Id])
-			if err != nil {
-				// retry (once)
-				logger.warn("Couldn't query notices from API endpoint '" + a.Id + "'. Retrying ...")
-				logger.warn(err)
-				n, t, err = a.getNotices(persistent.data.(PersistentData).LastPublished[a.Id])
+		rt.enricher.refresh(rt.httpClient)
+		for _, s := range rt.sources {
+			logger.info("Querying " + s.sourceName() + " for new notices ...")
+			since := persistent.data.(PersistentData).LastPublished[s.sourceId()]
+			n, t, err := queryNotices(s, rt.httpClient, since)
+			if err == nil && len(n) > 0 {
+				newNotices = append(newNotices, detectRevisions(n, since, persistent.data.(PersistentData).Fingerprints, fingerprints)...)
+				lastPublished[s.sourceId()] = t
+			} else if err == nil {
+				quietSources = append(quietSources, s.sourceId())
+			}
+		}
+		logger.debug(fmt.Sprintf("Got %v new or changed notices", len(newNotices)))
+		rt.enricher.enrich(newNotices)
+		// notices held until the next delivery window, per list
+		held := map[string][]WidNotice{}
+		for _, l := range *rt.config.Lists {
+			// lists that were removed from the config are left out
+			if h := persistent.data.(PersistentData).Held[l.Name]; len(h) > 0 {
+				held[l.Name] = slices.Clone(h)
+			}
+		}
+		// held notices that recipients already got through other lists
+		sent := map[string]map[string]string{}
+		for address, s := range persistent.data.(PersistentData).Sent {
+			sent[address] = maps.Clone(s)
+		}
+		saveProgress := func() {
+			for id, t := range lastPublished {
+				persistent.data.(PersistentData).LastPublished[id] = t
+			}
+			for id, fp := range fingerprints {
+				persistent.data.(PersistentData).Fingerprints[id] = fp
 			}
-			if err != nil {
-				// ok then...
-				logger.error("Couldn't query notices from API endpoint '" + a.Id + "'")
-				logger.error(err)
-			} else if len(n) > 0 {
-				newNotices = append(newNotices, n...)
-				lastPublished[a.Id] = t
+			refreshFingerprints(persistent.data.(PersistentData).Fingerprints, quietSources, time.Now())
+			pruneFingerprints(persistent.data.(PersistentData).Fingerprints)
+			clear(persistent.data.(PersistentData).Held)
+			maps.Copy(persistent.data.(PersistentData).Held, held)
+			pruneSent(sent, held)
+			clear(persistent.data.(PersistentData).Sent)
+			maps.Copy(persistent.data.(PersistentData).Sent, sent)
+			persistent.save()
+			rt.httpClient.commitValidators()
+		}
+		// list name : notices of this list
+		listNotices := map[string][]WidNotice{}
+		now := time.Now()
+		for _, l := range *rt.config.Lists {
+			// Filter notices for this list
+			listNotices[l.Name] = l.deliverableNotices(l.filterNotices(newNotices), held, now)
+		}
+		// subscriptions can change at any time
+		recipients := allRecipients(rt.config, subscriptions.all())
+		// mail recipient : pointer to slice of wid notices to be sent
+		noticesToBeSent := map[string][]*WidNotice{}
+		for _, r := range recipients {
+			for _, n := range r.selectNotices(listNotices, newNotices) {
+				if alreadySent(sent, r.Address, n) {
+					logger.debug(fmt.Sprintf("Notice %v left out for recipient %v: already sent through another list", n.Name, r.Address))
+					continue
+				}
+				np := &n
+				if !noticeSliceContains(noticesToBeSent[r.Address], np) {
+					noticesToBeSent[r.Address] = append(noticesToBeSent[r.Address], np)
+				}
 			}
 		}
-		logger.debug(fmt.Sprintf("Got %v new notices", len(newNotices)))
-		if len(newNotices) > 0 {
+		if len(noticesToBeSent) < 1 {
+			// nothing to send, but fingerprints or held notices may have changed
+			saveProgress()
+		} else {
 			logger.info("Sending email notifications ...")
-			// mail recipient : pointer to slice of wid notices to be sent
-			noticesToBeSent := map[string][]*WidNotice{}
 			recipientsNotified := 0
 			var err error
-			for _, l := range *config.Lists {
-				// Filter notices for this list
-				for _, f := range l.Filter {
-					for _, n := range f.filter(newNotices) {
-						np := &n
-						for _, r := range l.Recipients {
-							if !noticeSliceContains(noticesToBeSent[r], np) {
-								noticesToBeSent[r] = append(noticesToBeSent[r], np)
-							}
-						}
-					}
+			// sorted for reproducible results
+			for _, r := range recipients {
+				notices := noticesToBeSent[r.Address]
+				if len(notices) < 1 {
+					continue
 				}
-			}
-			for r, notices := range noticesToBeSent {
 				// sort by publish date
 				slices.SortFunc(notices, func(a *WidNotice, b *WidNotice) int {
 					if a.Published == b.Published {
@@ -149,24 +265,55 @@ func main() {
 					}
 				})
 				// send
-				err = sendNotices(r, notices, mailTemplate, mailAuth, config.SmtpConfiguration, &cache)
+				err = sendNotices(r, notices, rt.mailTemplates, rt.mailAuth, rt.config.SmtpConfiguration, rt.config.Portal, rt.httpClient, &cache)
 				if err != nil {
 					logger.error(err)
 				} else {
 					recipientsNotified++
+					recordSent(sent, held, r.Address, notices)
 				}
 			}
 			if recipientsNotified < 1 && err != nil {
 				logger.error("Couldn't send any mail notification!")
+				// query the same notices again on the next try
+				rt.httpClient.discardValidators()
 			} else {
-				for id, t := range lastPublished {
-					persistent.data.(PersistentData).LastPublished[id] = t
-					persistent.save()
-				}
+				saveProgress()
 				logger.info(fmt.Sprintf("Email notifications sent to %v of %v recipients", recipientsNotified, len(noticesToBeSent)))
 			}
 		}
-		dt := int(time.Now().UnixMilli() - t1)
-		time.Sleep(time.Millisecond * time.Duration((config.ApiFetchInterval * 1000) - dt))
+		if once {
+			break
+		}
+		// wait for the next cycle, the configuration is only swapped in between
+		next := t1.Add(time.Second * time.Duration(rt.config.ApiFetchInterval))
+		for waiting := true; waiting; {
+			select {
+			case <-time.After(time.Until(next)):
+				waiting = false
+			case <-reload:
+				logger.info("Reloading configuration file " + configFilePath + " ...")
+				newRt, err := reloadConfig(configFilePath, rt, subscriptions)
+				if err != nil {
+					logger.error(err)
+					logger.error("Couldn't reload configuration, keeping the current configuration")
+					continue
+				}
+				changes := configChanges(rt.config, newRt.config)
+				for _, c := range changes {
+					logger.info("Configuration: " + c)
+				}
+				if len(changes) < 1 {
+					logger.info("Configuration: nothing changed")
+				}
+				rt = newRt
+				logger.LogLevel = rt.config.LogLevel
+				initLastPublished(rt.config)
+				if portal != nil {
+					portal.update(rt.config, rt.mailAuth)
+				}
+				next = t1.Add(time.Second * time.Duration(rt.config.ApiFetchInterval))
+			}
+		}
 	}
 }
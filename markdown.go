@@ -0,0 +1,579 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A minimal Markdown renderer for Markdown templates. It supports headings,
+// paragraphs, (nested) lists, block quotes, fenced code blocks, rules,
+// emphasis, code spans, links and bare urls - enough for notifications,
+// but not CommonMark.
+
+type markdownFormat int
+
+const (
+	markdownText markdownFormat = iota // plain text
+	markdownHtml
+	markdownSlack // Slack mrkdwn
+)
+
+type mdBlock struct {
+	kind string // "heading", "paragraph", "list", "quote", "code" or "rule"
+	level int // heading
+	ordered bool // list
+	start int // first number of an ordered list
+	lines []string // heading, paragraph and code
+	items [][]mdBlock // list
+	children []mdBlock // quote
+}
+
+type mdInline struct {
+	kind string // "text", "code", "strong", "em", "link", "softbreak" or "break"
+	text string // text and code
+	url string // link
+	children []mdInline // strong, em and link
+}
+
+var mdHeadingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+var mdRulePattern = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+var mdFencePattern = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+var mdQuotePattern = regexp.MustCompile(`^ {0,3}> ?`)
+var mdListPattern = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])( +|$)`)
+var mdUrlPattern = regexp.MustCompile(`^(?:https?://|mailto:)[^\s<>]+`)
+
+// markdownToText renders the Markdown text as plain text
+func markdownToText(src string) string {
+	return renderMarkdownBlocks(parseMarkdown(src), markdownText, "\n\n") + "\n"
+}
+
+// markdownToSlack renders the Markdown text as Slack mrkdwn
+func markdownToSlack(src string) string {
+	return renderMarkdownBlocks(parseMarkdown(src), markdownSlack, "\n\n")
+}
+
+// markdownToHtml renders the Markdown text as HTML document
+func markdownToHtml(src string) string {
+	return "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"></head>\n<body>\n" +
+		renderMarkdownBlocksHtml(parseMarkdown(src), false) +
+		"</body>\n</html>\n"
+}
+
+func parseMarkdown(src string) []mdBlock {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		// expand leading tabs
+		trimmed := strings.TrimLeft(l, " \t")
+		indent := l[:len(l) - len(trimmed)]
+		lines[i] = strings.ReplaceAll(indent, "\t", "    ") + trimmed
+	}
+	return parseMarkdownBlocks(lines)
+}
+
+func mdStartsBlock(line string) bool {
+	return mdHeadingPattern.MatchString(line) || mdRulePattern.MatchString(line) ||
+		mdFencePattern.MatchString(line) || mdQuotePattern.MatchString(line) ||
+		mdListPattern.MatchString(line)
+}
+
+// mdLoose checks if a list item has multiple paragraphs
+func mdLoose(item []mdBlock) bool {
+	paragraphs := 0
+	for _, b := range item {
+		if b.kind == "paragraph" {
+			paragraphs++
+		}
+	}
+	return paragraphs > 1
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func parseMarkdownBlocks(lines []string) []mdBlock {
+	blocks := []mdBlock{}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			i++
+			continue
+		}
+		if m := mdFencePattern.FindStringSubmatch(line); m != nil {
+			b := mdBlock{kind: "code", lines: []string{}}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				b.lines = append(b.lines, lines[i])
+			}
+			i++ // closing fence
+			blocks = append(blocks, b)
+			continue
+		}
+		if m := mdHeadingPattern.FindStringSubmatch(line); m != nil {
+			blocks = append(blocks, mdBlock{kind: "heading", level: len(m[1]), lines: []string{m[2]}})
+			i++
+			continue
+		}
+		if mdRulePattern.MatchString(line) {
+			blocks = append(blocks, mdBlock{kind: "rule"})
+			i++
+			continue
+		}
+		if mdQuotePattern.MatchString(line) {
+			quoted := []string{}
+			for ; i < len(lines) && mdQuotePattern.MatchString(lines[i]); i++ {
+				quoted = append(quoted, mdQuotePattern.ReplaceAllString(lines[i], ""))
+			}
+			blocks = append(blocks, mdBlock{kind: "quote", children: parseMarkdownBlocks(quoted)})
+			continue
+		}
+		if mdListPattern.MatchString(line) {
+			b, n := parseMarkdownList(lines[i:])
+			blocks = append(blocks, b)
+			i += n
+			continue
+		}
+		b := mdBlock{kind: "paragraph"}
+		for ; i < len(lines) && !isBlank(lines[i]) && (len(b.lines) < 1 || !mdStartsBlock(lines[i])); i++ {
+			b.lines = append(b.lines, strings.TrimLeft(lines[i], " "))
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// parseMarkdownList parses the list at the beginning of the lines and
+// returns it with the number of lines it spans
+func parseMarkdownList(lines []string) (mdBlock, int) {
+	first := mdListPattern.FindStringSubmatch(lines[0])
+	b := mdBlock{kind: "list", ordered: !strings.ContainsAny(first[2], "-*+")}
+	if b.ordered {
+		b.start, _ = strconv.Atoi(first[2][:len(first[2]) - 1])
+	}
+	marker := first[2][len(first[2]) - 1:]
+	items := [][]string{}
+	indent := 0 // of the content of the current item
+	// startsItem checks if the line starts another item of this list
+	startsItem := func(line string) ([]string, bool) {
+		m := mdListPattern.FindStringSubmatch(line)
+		if m == nil || len(m[1]) >= indent && len(items) > 0 || mdRulePattern.MatchString(line) {
+			return nil, false
+		}
+		return m, true
+	}
+	i := 0
+	for i < len(lines) {
+		line := lines[i]
+		if m, ok := startsItem(line); ok {
+			if !strings.HasSuffix(m[2], marker) {
+				break // another list
+			}
+			indent = len(m[0])
+			if m[3] == "" {
+				indent = len(m[1]) + len(m[2]) + 1
+			}
+			items = append(items, []string{line[len(m[0]):]})
+			i++
+			continue
+		}
+		item := &items[len(items) - 1]
+		if isBlank(line) {
+			// the item or the list continues after blank lines if the
+			// next line is indented or another item
+			j := i
+			for j < len(lines) && isBlank(lines[j]) {
+				j++
+			}
+			if j >= len(lines) { break }
+			_, next := startsItem(lines[j])
+			if !next && len(lines[j]) - len(strings.TrimLeft(lines[j], " ")) < indent {
+				break
+			}
+			for ; i < j; i++ {
+				*item = append(*item, "")
+			}
+			continue
+		}
+		if len(line) - len(strings.TrimLeft(line, " ")) >= indent {
+			*item = append(*item, line[indent:])
+		} else if !mdStartsBlock(line) && !isBlank((*item)[len(*item) - 1]) {
+			// lazy continuation of a paragraph
+			*item = append(*item, line)
+		} else {
+			break
+		}
+		i++
+	}
+	for _, item := range items {
+		b.items = append(b.items, parseMarkdownBlocks(item))
+	}
+	return b, i
+}
+
+// mdClosing returns the position of the delimiter that closes the
+// emphasis starting at start, or -1
+func mdClosing(s string, start int, delim string) int {
+	c := delim[0]
+	for j := start + 1; j + len(delim) <= len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j:j + len(delim)] != delim || s[j - 1] == ' ' || s[j - 1] == '\n' {
+			continue
+		}
+		after := j + len(delim)
+		if len(delim) == 1 && (s[j - 1] == c || after < len(s) && s[after] == c) {
+			continue // part of a double delimiter
+		}
+		if c == '_' && after < len(s) && isWordChar(s[after]) {
+			continue // inside a word, e.g. snake_case
+		}
+		return j
+	}
+	return -1
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func parseMarkdownInline(s string) []mdInline {
+	nodes := []mdInline{}
+	text := strings.Builder{}
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, mdInline{kind: "text", text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i + 1 < len(s) && s[i + 1] == '\n':
+			flush()
+			nodes = append(nodes, mdInline{kind: "break"})
+			i += 2
+			continue
+		case c == '\\' && i + 1 < len(s) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", s[i + 1]) >= 0:
+			text.WriteByte(s[i + 1])
+			i += 2
+			continue
+		case c == '\n':
+			// two spaces at the end of a line are a hard line break
+			t := text.String()
+			text.Reset()
+			text.WriteString(strings.TrimRight(t, " "))
+			flush()
+			if strings.HasSuffix(t, "  ") {
+				nodes = append(nodes, mdInline{kind: "break"})
+			} else {
+				nodes = append(nodes, mdInline{kind: "softbreak"})
+			}
+			i++
+			continue
+		case c == '`':
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			if end := strings.Index(s[i + n:], s[i:i + n]); end >= 0 {
+				flush()
+				code := strings.ReplaceAll(s[i + n:i + n + end], "\n", " ")
+				if len(code) > 1 && code[0] == ' ' && code[len(code) - 1] == ' ' {
+					code = code[1:len(code) - 1]
+				}
+				nodes = append(nodes, mdInline{kind: "code", text: code})
+				i += 2 * n + end
+				continue
+			}
+			text.WriteString(s[i:i + n])
+			i += n
+			continue
+		case c == '*' || c == '_':
+			canOpen := func(after int) bool {
+				return after < len(s) && s[after] != ' ' && s[after] != '\n' && (c == '*' || i == 0 || !isWordChar(s[i - 1]))
+			}
+			delim := s[i:i + 1]
+			if triple := strings.Repeat(delim, 3); strings.HasPrefix(s[i:], triple) && canOpen(i + 3) {
+				if end := mdClosing(s, i + 3, triple); end >= 0 {
+					flush()
+					strong := mdInline{kind: "strong", children: parseMarkdownInline(s[i + 3:end])}
+					nodes = append(nodes, mdInline{kind: "em", children: []mdInline{strong}})
+					i = end + 3
+					continue
+				}
+			}
+			if strings.HasPrefix(s[i:], delim + delim) {
+				delim += delim
+			}
+			after := i + len(delim)
+			if canOpen(after) {
+				if end := mdClosing(s, after, delim); end >= 0 {
+					flush()
+					kind := "em"
+					if len(delim) == 2 {
+						kind = "strong"
+					}
+					nodes = append(nodes, mdInline{kind: kind, children: parseMarkdownInline(s[after:end])})
+					i = end + len(delim)
+					continue
+				}
+			}
+			text.WriteString(delim)
+			i += len(delim)
+			continue
+		case c == '[':
+			if end := mdClosingBracket(s, i, '[', ']'); end >= 0 && strings.HasPrefix(s[end + 1:], "(") {
+				if close := mdClosingBracket(s, end + 1, '(', ')'); close >= 0 {
+					flush()
+					target := strings.TrimSpace(s[end + 2:close])
+					if k := strings.IndexByte(target, '>'); strings.HasPrefix(target, "<") && k > 0 {
+						// <...> can contain spaces
+						target = strings.ReplaceAll(target[1:k], " ", "%20")
+					} else if k := strings.IndexAny(target, " \t\n"); k >= 0 {
+						target = target[:k] // without title
+					}
+					label := parseMarkdownInline(s[i + 1:end])
+					if u, err := url.Parse(target); target == "" || err != nil || u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "mailto" {
+						nodes = append(nodes, label...) // e.g. javascript:
+					} else {
+						nodes = append(nodes, mdInline{kind: "link", url: target, children: label})
+					}
+					i = close + 1
+					continue
+				}
+			}
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				u := s[i + 1:i + end]
+				if mdUrlPattern.FindString(u) == u {
+					flush()
+					nodes = append(nodes, mdInline{kind: "link", url: u, children: []mdInline{{kind: "text", text: u}}})
+					i += end + 1
+					continue
+				}
+			}
+		case (c == 'h' || c == 'm') && (i == 0 || !isWordChar(s[i - 1])):
+			// bare url
+			if u := strings.TrimRight(mdUrlPattern.FindString(s[i:]), ".,:;!?'\")]*_"); u != "" {
+				flush()
+				nodes = append(nodes, mdInline{kind: "link", url: u, children: []mdInline{{kind: "text", text: u}}})
+				i += len(u)
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return nodes
+}
+
+// mdClosingBracket returns the position of the bracket closing the one at
+// start, or -1
+func mdClosingBracket(s string, start int, open byte, close byte) int {
+	depth := 0
+	for j := start; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// prefixLines prefixes the first line with first and the other
+// non-empty lines with rest
+func prefixLines(s string, first string, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		p := rest
+		if i == 0 {
+			p = first
+		}
+		lines[i] = strings.TrimRight(p + l, " ")
+		if l == "" && i > 0 && strings.TrimSpace(p) == "" {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// renderMarkdownBlocks renders the blocks as plain text or Slack mrkdwn
+func renderMarkdownBlocks(blocks []mdBlock, format markdownFormat, sep string) string {
+	parts := []string{}
+	for _, b := range blocks {
+		switch b.kind {
+		case "heading":
+			t := renderMarkdownInline(parseMarkdownInline(b.lines[0]), format)
+			if format == markdownSlack {
+				t = "*" + t + "*"
+			} else if b.level == 1 {
+				t += "\n" + strings.Repeat("=", utf8.RuneCountInString(t))
+			} else if b.level == 2 {
+				t += "\n" + strings.Repeat("-", utf8.RuneCountInString(t))
+			}
+			parts = append(parts, t)
+		case "paragraph":
+			parts = append(parts, renderMarkdownInline(parseMarkdownInline(strings.TrimSpace(strings.Join(b.lines, "\n"))), format))
+		case "list":
+			items := []string{}
+			for j, item := range b.items {
+				marker := "- "
+				if b.ordered {
+					marker = strconv.Itoa(b.start + j) + ". "
+				} else if format == markdownSlack {
+					marker = "• "
+				}
+				indent := strings.Repeat(" ", utf8.RuneCountInString(marker))
+				itemSep := "\n"
+				if mdLoose(item) {
+					itemSep = "\n\n"
+				}
+				items = append(items, prefixLines(renderMarkdownBlocks(item, format, itemSep), marker, indent))
+			}
+			parts = append(parts, strings.Join(items, "\n"))
+		case "quote":
+			parts = append(parts, prefixLines(renderMarkdownBlocks(b.children, format, "\n\n"), "> ", "> "))
+		case "code":
+			code := strings.Join(b.lines, "\n")
+			if format == markdownSlack {
+				parts = append(parts, "```\n" + slackEscape(code) + "\n```")
+			} else {
+				parts = append(parts, prefixLines(code, "    ", "    "))
+			}
+		case "rule":
+			parts = append(parts, strings.Repeat("-", 40))
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+func renderMarkdownInline(nodes []mdInline, format markdownFormat) string {
+	b := strings.Builder{}
+	for _, n := range nodes {
+		switch n.kind {
+		case "text":
+			if format == markdownSlack {
+				b.WriteString(slackEscape(n.text))
+			} else {
+				b.WriteString(n.text)
+			}
+		case "code":
+			if format == markdownSlack {
+				b.WriteString("`" + slackEscape(n.text) + "`")
+			} else {
+				b.WriteString(n.text)
+			}
+		case "strong":
+			if format == markdownSlack {
+				b.WriteString("*" + renderMarkdownInline(n.children, format) + "*")
+			} else {
+				b.WriteString(renderMarkdownInline(n.children, format))
+			}
+		case "em":
+			if format == markdownSlack {
+				b.WriteString("_" + renderMarkdownInline(n.children, format) + "_")
+			} else {
+				b.WriteString(renderMarkdownInline(n.children, format))
+			}
+		case "link":
+			label := renderMarkdownInline(n.children, format)
+			// | would end the url and start the label
+			u := strings.ReplaceAll(slackEscape(n.url), "|", "%7C")
+			if format == markdownSlack && !strings.Contains(n.url, "://") && !strings.HasPrefix(n.url, "mailto:") {
+				// Slack can't open relative links
+				b.WriteString(label + " (" + slackEscape(n.url) + ")")
+			} else if format == markdownSlack && label == slackEscape(n.url) {
+				b.WriteString("<" + u + ">")
+			} else if format == markdownSlack {
+				b.WriteString("<" + u + "|" + label + ">")
+			} else if label == n.url {
+				b.WriteString(n.url)
+			} else {
+				b.WriteString(label + " (" + n.url + ")")
+			}
+		case "softbreak", "break":
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// renderMarkdownBlocksHtml renders the blocks as HTML, paragraphs without
+// <p> if tight (in list items)
+func renderMarkdownBlocksHtml(blocks []mdBlock, tight bool) string {
+	b := strings.Builder{}
+	for _, bl := range blocks {
+		switch bl.kind {
+		case "heading":
+			fmt.Fprintf(&b, "<h%v>%v</h%v>\n", bl.level, renderMarkdownInlineHtml(parseMarkdownInline(bl.lines[0])), bl.level)
+		case "paragraph":
+			t := renderMarkdownInlineHtml(parseMarkdownInline(strings.TrimSpace(strings.Join(bl.lines, "\n"))))
+			if tight {
+				b.WriteString(t + "\n")
+			} else {
+				b.WriteString("<p>" + t + "</p>\n")
+			}
+		case "list":
+			tag := "ul"
+			if bl.ordered {
+				tag = "ol"
+			}
+			if bl.ordered && bl.start != 1 {
+				fmt.Fprintf(&b, "<ol start=\"%v\">\n", bl.start)
+			} else {
+				b.WriteString("<" + tag + ">\n")
+			}
+			for _, item := range bl.items {
+				b.WriteString("<li>" + strings.TrimSuffix(renderMarkdownBlocksHtml(item, !mdLoose(item)), "\n") + "</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+		case "quote":
+			b.WriteString("<blockquote>\n" + renderMarkdownBlocksHtml(bl.children, false) + "</blockquote>\n")
+		case "code":
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(bl.lines, "\n")) + "</code></pre>\n")
+		case "rule":
+			b.WriteString("<hr>\n")
+		}
+	}
+	return b.String()
+}
+
+func renderMarkdownInlineHtml(nodes []mdInline) string {
+	b := strings.Builder{}
+	for _, n := range nodes {
+		switch n.kind {
+		case "text":
+			b.WriteString(html.EscapeString(n.text))
+		case "code":
+			b.WriteString("<code>" + html.EscapeString(n.text) + "</code>")
+		case "strong":
+			b.WriteString("<strong>" + renderMarkdownInlineHtml(n.children) + "</strong>")
+		case "em":
+			b.WriteString("<em>" + renderMarkdownInlineHtml(n.children) + "</em>")
+		case "link":
+			b.WriteString("<a href=\"" + html.EscapeString(n.url) + "\">" + renderMarkdownInlineHtml(n.children) + "</a>")
+		case "softbreak":
+			b.WriteString("\n")
+		case "break":
+			b.WriteString("<br>\n")
+		}
+	}
+	return b.String()
+}
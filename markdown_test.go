@@ -0,0 +1,105 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestMarkdownGolden renders testdata/markdown/*.md as text, HTML and Slack
+// mrkdwn and compares the results with the .txt, .html and .slack files.
+// Run with -update to rewrite them.
+func TestMarkdownGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/markdown/*.md")
+	if err != nil { t.Fatal(err) }
+	if len(inputs) < 1 {
+		t.Fatal("no test inputs")
+	}
+	formats := []struct {
+		ext string
+		render func(string) string
+	}{
+		{".txt", markdownToText},
+		{".html", markdownToHtml},
+		{".slack", markdownToSlack},
+	}
+	for _, input := range inputs {
+		src, err := os.ReadFile(input)
+		if err != nil { t.Fatal(err) }
+		for _, f := range formats {
+			golden := strings.TrimSuffix(input, ".md") + f.ext
+			t.Run(filepath.Base(golden), func(t *testing.T) {
+				got := f.render(string(src))
+				if *updateGolden {
+					if err := os.WriteFile(golden, []byte(got), 0644); err != nil { t.Fatal(err) }
+					return
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil { t.Fatal(err) }
+				if got != string(want) {
+					t.Errorf("output differs from %v\n--- got:\n%v\n--- want:\n%s", golden, got, want)
+				}
+			})
+		}
+	}
+}
+
+// TestMarkdownTemplateEscaping checks that HTML and links in notice data
+// can't get into the HTML part of mails from Markdown templates
+func TestMarkdownTemplateEscaping(t *testing.T) {
+	funcs := template.FuncMap{"escapeMarkdown": func(s string) string { return markdownSpecialChars.ReplaceAllString(s, "\\$1") }}
+	tc := MailTemplateConfig{
+		SubjectTemplate: "{{ .Title }}",
+		MarkdownTemplate: "## {{ .Title }}\n\n" +
+			"{{ escapeMarkdown .Title }}\n\n" +
+			"- {{ .Name }}\n" +
+			"{{ range .ProductNames }}- {{ . }}\n{{ end }}\n" +
+			"[Details]({{ .PortalUrl }})\n",
+	}
+	mt, err := NewTemplateFromTemplateConfig("test", tc, funcs)
+	if err != nil { t.Fatal(err) }
+	tests := []struct {
+		name string
+		notice WidNotice
+		forbidden []string
+	}{
+		{"html in the title", WidNotice{
+			Title: `<script>alert("x")</script> <img src=x onerror=alert(1)> & <b>bold</b>`,
+			Name: "<iframe src=//evil.example>",
+			ProductNames: []string{"<svg onload=alert(1)>"},
+			PortalUrl: "https://example.org",
+		}, []string{"<script", "<img", "<b>", "<iframe", "<svg"}},
+		{"javascript url", WidNotice{
+			Title: "[click](javascript:alert(1))",
+			Name: "x",
+			PortalUrl: "javascript:alert(document.cookie)",
+		}, []string{"<a ", "href"}},
+		{"quotes in the url", WidNotice{
+			Title: "x",
+			Name: "x",
+			PortalUrl: `https://example.org/?a="><script>alert(1)</script>`,
+		}, []string{"<script", `"><`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, err := mt.generate(TemplateData{&tt.notice, "test"})
+			if err != nil { t.Fatal(err) }
+			body := mc.Html[strings.Index(mc.Html, "<body>"):]
+			for _, f := range tt.forbidden {
+				if strings.Contains(body, f) {
+					t.Errorf("HTML contains %q:\n%v", f, body)
+				}
+			}
+			if !strings.Contains(body, "&lt;") && strings.Contains(tt.notice.Title, "<") {
+				t.Errorf("HTML doesn't contain the escaped title:\n%v", body)
+			}
+		})
+	}
+}
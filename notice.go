@@ -20,9 +20,30 @@ type WidNotice struct {
 	ProductNames []string `json:"productNames"` // empty = unknown
 	Cves []string `json:"cves"` // empty = unknown
 	NoPatch string `json:"noPatch"` // "" = unknown
+	Cpes []string `json:"cpes"` // empty = unknown (only CSAF sources)
 	// metadata
 	ApiEndpointId string
 	PortalUrl string
+	// revisions
+	Event string // "new" or "changed"
+	Changes []FieldChange // for "changed" events
+	// enrichment
+	CveDetails []CveDetails
+	Kev bool // any CVE is in the CISA KEV catalog
+	MaxEpss float64 // highest EPSS probability of all CVEs
+	// lists with an asset inventory
+	MatchedAssets []Asset
+	// the lists the notice was selected from, for the current recipient
+	Lists []string
+}
+
+// cacheKey identifies the generated mail content for this notice
+func (n *WidNotice) cacheKey() string {
+	key := n.Uuid
+	for _, a := range n.MatchedAssets {
+		key += "|" + a.String()
+	}
+	return key
 }
 
 func noticeSliceContains(notices []*WidNotice, notice *WidNotice) bool {
@@ -33,3 +54,12 @@ func noticeSliceContains(notices []*WidNotice, notice *WidNotice) bool {
 	}
 	return false
 }
+
+func noticeSliceContainsValue(notices []WidNotice, notice WidNotice) bool {
+	for _, x := range notices {
+		if x.Uuid == notice.Uuid {
+			return true
+		}
+	}
+	return false
+}
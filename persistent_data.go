@@ -9,11 +9,23 @@ import (
 type PersistentData struct {
 	// {endpoint id 1: time last published, endpoint id 2: ..., ...}
 	LastPublished map[string]time.Time `json:"last_published"`
+	// {notice uuid 1: fingerprint, ...}
+	Fingerprints map[string]NoticeFingerprint `json:"fingerprints"`
+	// {list name: notices held until the next delivery window, ...}
+	Held map[string][]WidNotice `json:"held"`
+	// {recipient address: {notice uuid: version, ...}, ...}, held notices
+	// that were already sent to the recipient through another list
+	Sent map[string]map[string]string `json:"sent"`
 }
 
 func NewPersistentData(c Config) PersistentData {
 	// Initial persistent data
-	d := PersistentData{LastPublished: map[string]time.Time{}}
+	d := PersistentData{
+		LastPublished: map[string]time.Time{},
+		Fingerprints: map[string]NoticeFingerprint{},
+		Held: map[string][]WidNotice{},
+		Sent: map[string]map[string]string{},
+	}
 	for _, e := range apiEndpoints {
 		d.LastPublished[e.Id] = time.Now().Add(-time.Hour * 24) // a day ago
 	}
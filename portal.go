@@ -0,0 +1,542 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Self-service portal: recipients log in with a link that is sent to them
+// by mail and manage their subscriptions. Changes are saved to the
+// subscriptions file (see subscriptions.go). The portal doesn't do TLS, run
+// it behind a reverse proxy.
+
+const PORTAL_LOGIN_LINK_VALIDITY = time.Minute * 15
+const PORTAL_SESSION_VALIDITY = time.Hour * 24 * 7
+const PORTAL_LOGIN_MAIL_INTERVAL = time.Minute // per address
+const PORTAL_SESSION_COOKIE = "wid_session"
+
+type PortalSettings struct {
+	Listen string `json:"listen"` // e.g. "127.0.0.1:8080", "" = disabled
+	BaseUrl string `json:"base_url"` // public url of the portal, used for links in mails
+	Secret string `json:"secret"` // key for signing links and cookies
+	SecretFile string `json:"secret_file"`
+	SecretCommand string `json:"secret_command"`
+	SubscriptionsFile string `json:"subscriptions_file"`
+	// addresses of these domains can log in, besides the configured recipients
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+func (s PortalSettings) enabled() bool {
+	return s.Listen != ""
+}
+
+func (s PortalSettings) check() error {
+	if len(s.Secret) < 16 {
+		return errors.New("the secret of the portal must have at least 16 characters")
+	}
+	u, err := url.Parse(s.BaseUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid base url of the portal '" + s.BaseUrl + "'")
+	}
+	if s.SubscriptionsFile == "" {
+		return errors.New("the portal needs a subscriptions file")
+	}
+	return nil
+}
+
+// unsubscribeUrl returns a link that removes the recipient from the lists,
+// or from everything if no lists are given
+func unsubscribeUrl(s PortalSettings, address string, lists []string) string {
+	value := strings.Join(append([]string{strings.ToLower(address)}, lists...), "\t")
+	return strings.TrimSuffix(s.BaseUrl, "/") + "/unsubscribe?token=" + url.QueryEscape(signToken(s.Secret, "unsubscribe", value, time.Time{}))
+}
+
+// signed tokens: base64(purpose \n value \n expiry).base64(hmac)
+
+func signToken(secret string, purpose string, value string, expires time.Time) string {
+	expiry := int64(0) // doesn't expire
+	if !expires.IsZero() {
+		expiry = expires.Unix()
+	}
+	payload := []byte(fmt.Sprintf("%v\n%v\n%v", purpose, value, expiry))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyToken returns the value of a token signed for this purpose
+func verifyToken(secret string, purpose string, token string) (string, error) {
+	invalid := errors.New("invalid token")
+	p, s, ok := strings.Cut(token, ".")
+	if !ok { return "", invalid }
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil { return "", invalid }
+	signature, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil { return "", invalid }
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) { return "", invalid }
+	parts := strings.Split(string(payload), "\n")
+	if len(parts) != 3 || parts[0] != purpose { return "", invalid }
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil { return "", invalid }
+	if expiry != 0 && time.Now().Unix() > expiry {
+		return "", errors.New("token expired")
+	}
+	return parts[1], nil
+}
+
+type Portal struct {
+	config Config
+	subscriptions *SubscriptionStore
+	auth smtp.Auth
+	configMutex sync.RWMutex // config and auth are replaced on reload
+	loginMails map[string]time.Time // address : last login mail
+	usedLogins map[string]time.Time // signature of used login tokens : expiry
+	mutex sync.Mutex
+}
+
+func NewPortal(config Config, subscriptions *SubscriptionStore, auth smtp.Auth) *Portal {
+	return &Portal{
+		config: config,
+		subscriptions: subscriptions,
+		auth: auth,
+		loginMails: map[string]time.Time{},
+		usedLogins: map[string]time.Time{},
+	}
+}
+
+// update replaces the configuration after it was reloaded
+func (p *Portal) update(config Config, auth smtp.Auth) {
+	p.configMutex.Lock()
+	defer p.configMutex.Unlock()
+	p.config = config
+	p.auth = auth
+}
+
+// current returns the current configuration and mail authentication
+func (p *Portal) current() (Config, smtp.Auth) {
+	p.configMutex.RLock()
+	defer p.configMutex.RUnlock()
+	return p.config, p.auth
+}
+
+func (p *Portal) settings() PortalSettings {
+	config, _ := p.current()
+	return config.Portal
+}
+
+// recipient returns the recipient with its current subscriptions
+func (p *Portal) recipient(address string) Recipient {
+	config, _ := p.current()
+	for _, r := range allRecipients(config, p.subscriptions.all()) {
+		if strings.EqualFold(r.Address, address) {
+			return r
+		}
+	}
+	return Recipient{Address: address}
+}
+
+// configuredLists returns the lists of the recipient without subscriptions
+func (p *Portal) configuredLists(address string) []string {
+	config, _ := p.current()
+	for _, r := range allRecipients(config, nil) {
+		if strings.EqualFold(r.Address, address) {
+			return r.Lists
+		}
+	}
+	return []string{}
+}
+
+func (p *Portal) mayLogIn(address string) bool {
+	_, domain, _ := strings.Cut(address, "@")
+	if slices.ContainsFunc(p.settings().AllowedDomains, func(d string) bool { return strings.EqualFold(d, domain) }) {
+		return true
+	}
+	if _, ok := p.subscriptions.all()[strings.ToLower(address)]; ok {
+		return true
+	}
+	config, _ := p.current()
+	for _, r := range allRecipients(config, nil) {
+		if strings.EqualFold(r.Address, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// session returns the address of the logged in recipient
+func (p *Portal) session(r *http.Request) (string, bool) {
+	c, err := r.Cookie(PORTAL_SESSION_COOKIE)
+	if err != nil { return "", false }
+	address, err := verifyToken(p.settings().Secret, "session", c.Value)
+	return address, err == nil
+}
+
+func (p *Portal) csrfToken(r *http.Request) string {
+	c, err := r.Cookie(PORTAL_SESSION_COOKIE)
+	if err != nil { return "" }
+	mac := hmac.New(sha256.New, []byte(p.settings().Secret))
+	mac.Write([]byte("csrf\n" + c.Value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (p *Portal) checkCsrf(r *http.Request) bool {
+	t := p.csrfToken(r)
+	return t != "" && hmac.Equal([]byte(t), []byte(r.PostFormValue("csrf")))
+}
+
+type portalList struct {
+	Name string
+	Subscribed bool
+}
+
+type portalPage struct {
+	Address string
+	Message string
+	Error string
+	Lists []portalList
+	Products string
+	Titles string
+	MinBasescore int
+	Expression string
+	Revisions bool
+	Csrf string
+	Login string // token of the login confirmation
+	// unsubscribe confirmation
+	Unsubscribe string // token
+	UnsubscribeLists []string // empty = all
+}
+
+func (p *Portal) render(w http.ResponseWriter, status int, page portalPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+	if err := portalTemplate.Execute(w, page); err != nil {
+		logger.error(err)
+	}
+}
+
+func (p *Portal) page(r *http.Request, address string) portalPage {
+	page := portalPage{Address: address, Csrf: p.csrfToken(r)}
+	recipient := p.recipient(address)
+	config, _ := p.current()
+	for _, l := range *config.Lists {
+		page.Lists = append(page.Lists, portalList{l.Name, slices.Contains(recipient.Lists, l.Name)})
+	}
+	if f := p.subscriptions.get(address).Filter; len(f) > 0 {
+		page.Products = strings.Join(f[0].ProductsContain, ", ")
+		page.Titles = strings.Join(f[0].TitleContains, ", ")
+		page.MinBasescore = f[0].MinBaseScore
+		page.Expression = f[0].Expression
+		page.Revisions = f[0].Event == "all"
+	}
+	return page
+}
+
+func (p *Portal) handleIndex(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	address, ok := p.session(r)
+	if !ok {
+		p.render(w, http.StatusOK, portalPage{})
+		return
+	}
+	p.render(w, http.StatusOK, p.page(r, address))
+}
+
+// loginMailDue records a login mail to the address, unless one was sent
+// within PORTAL_LOGIN_MAIL_INTERVAL
+func (p *Portal) loginMailDue(address string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if time.Since(p.loginMails[address]) < PORTAL_LOGIN_MAIL_INTERVAL {
+		return false
+	}
+	for a, t := range p.loginMails {
+		if time.Since(t) >= PORTAL_LOGIN_MAIL_INTERVAL {
+			delete(p.loginMails, a)
+		}
+	}
+	p.loginMails[address] = time.Now()
+	return true
+}
+
+// loginToken returns the address of a login token that wasn't used before,
+// so forwarded or scanned links can't be used again. use marks it as used.
+func (p *Portal) loginToken(token string, use bool) (string, error) {
+	address, err := verifyToken(p.settings().Secret, "login", token)
+	if err != nil { return "", err }
+	_, signature, _ := strings.Cut(token, ".")
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for s, expiry := range p.usedLogins {
+		if time.Now().After(expiry) {
+			delete(p.usedLogins, s)
+		}
+	}
+	if _, used := p.usedLogins[signature]; used {
+		return "", errors.New("token already used")
+	}
+	if use {
+		// the token expires earlier
+		p.usedLogins[signature] = time.Now().Add(PORTAL_LOGIN_LINK_VALIDITY)
+	}
+	return address, nil
+}
+
+func (p *Portal) handleLogin(w http.ResponseWriter, r *http.Request) {
+	// the answer is always the same, so it doesn't reveal who is allowed to log in
+	page := portalPage{Message: "If you are allowed to use this portal, a login link was sent to your address."}
+	a, err := mail.ParseAddress(r.PostFormValue("address"))
+	if err != nil {
+		p.render(w, http.StatusBadRequest, portalPage{Error: "This is not a valid e-mail address."})
+		return
+	}
+	address := strings.ToLower(a.Address)
+	if !p.mayLogIn(address) || !p.loginMailDue(address) {
+		p.render(w, http.StatusOK, page)
+		return
+	}
+	token := signToken(p.settings().Secret, "login", address, time.Now().Add(PORTAL_LOGIN_LINK_VALIDITY))
+	link := strings.TrimSuffix(p.settings().BaseUrl, "/") + "/auth?token=" + url.QueryEscape(token)
+	mc := &MailContent{
+		Subject: "Login to WidNotifier",
+		Body: fmt.Sprintf("Open this link to manage your subscriptions:\n\n%v\n\nThe link is valid for %v minutes. If you didn't request it, you can ignore this mail.\n", link, int(PORTAL_LOGIN_LINK_VALIDITY.Minutes())),
+	}
+	config, auth := p.current()
+	if err := sendMails(config.SmtpConfiguration, auth, address, []*MailContent{mc}); err != nil {
+		logger.error("Couldn't send login link to " + address)
+		logger.error(err)
+	} else {
+		logger.info("Sent login link to " + address)
+	}
+	p.render(w, http.StatusOK, page)
+}
+
+// handleAuth asks for a confirmation on GET, so links opened by mail
+// scanners don't use up the login link. Each link can only be used once.
+func (p *Portal) handleAuth(w http.ResponseWriter, r *http.Request) {
+	invalid := portalPage{Error: "This login link is invalid, expired or was already used, please request a new one."}
+	token := r.URL.Query().Get("token")
+	if r.Method == http.MethodGet {
+		if _, err := p.loginToken(token, false); err != nil {
+			p.render(w, http.StatusForbidden, invalid)
+			return
+		}
+		p.render(w, http.StatusOK, portalPage{Login: token})
+		return
+	}
+	address, err := p.loginToken(token, true)
+	if err != nil {
+		p.render(w, http.StatusForbidden, invalid)
+		return
+	}
+	expires := time.Now().Add(PORTAL_SESSION_VALIDITY)
+	http.SetCookie(w, &http.Cookie{
+		Name: PORTAL_SESSION_COOKIE,
+		Value: signToken(p.settings().Secret, "session", address, expires),
+		Path: "/",
+		Expires: expires,
+		HttpOnly: true,
+		Secure: strings.HasPrefix(p.settings().BaseUrl, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (p *Portal) handleSave(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	address, ok := p.session(r)
+	if !ok || !p.checkCsrf(r) {
+		p.render(w, http.StatusForbidden, portalPage{Error: "Please log in again."})
+		return
+	}
+	r.ParseForm()
+	checked := r.PostForm["list"]
+	configured := p.configuredLists(address)
+	// also undoes unsubscribe_all
+	sub := Subscription{Lists: []string{}, Unsubscribed: []string{}}
+	config, _ := p.current()
+	for _, l := range *config.Lists {
+		if slices.Contains(checked, l.Name) && !slices.Contains(configured, l.Name) {
+			sub.Lists = append(sub.Lists, l.Name)
+		} else if !slices.Contains(checked, l.Name) && slices.Contains(configured, l.Name) {
+			sub.Unsubscribed = append(sub.Unsubscribed, l.Name)
+		}
+	}
+	split := func(s string) StringList {
+		l := StringList{}
+		for _, x := range strings.Split(s, ",") {
+			if x = strings.TrimSpace(x); x != "" {
+				l = append(l, x)
+			}
+		}
+		return l
+	}
+	f := Filter{
+		ProductsContain: split(r.PostFormValue("products")),
+		TitleContains: split(r.PostFormValue("titles")),
+		Expression: strings.TrimSpace(r.PostFormValue("expression")),
+		IgnoreCase: true,
+	}
+	f.MinBaseScore, _ = strconv.Atoi(r.PostFormValue("min_basescore"))
+	if r.PostFormValue("revisions") != "" {
+		f.Event = "all"
+	}
+	if len(f.ProductsContain) > 0 || len(f.TitleContains) > 0 || f.Expression != "" || f.MinBaseScore > 0 {
+		sub.Filter = []Filter{f}
+	}
+	if err := p.subscriptions.set(address, sub); err != nil {
+		page := p.page(r, address)
+		page.Error = "Your changes couldn't be saved: " + err.Error()
+		p.render(w, http.StatusBadRequest, page)
+		return
+	}
+	logger.info("Subscriptions of " + address + " changed")
+	page := p.page(r, address)
+	page.Message = "Your changes were saved."
+	p.render(w, http.StatusOK, page)
+}
+
+func (p *Portal) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if !p.checkCsrf(r) {
+		p.render(w, http.StatusForbidden, portalPage{Error: "Please log in again."})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: PORTAL_SESSION_COOKIE, Value: "", Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleUnsubscribe asks for a confirmation on GET, so links opened by mail
+// scanners don't unsubscribe anyone. POST is the one-click unsubscribe of
+// RFC 8058.
+func (p *Portal) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	value, err := verifyToken(p.settings().Secret, "unsubscribe", token)
+	if err != nil {
+		p.render(w, http.StatusForbidden, portalPage{Error: "This unsubscribe link is invalid."})
+		return
+	}
+	parts := strings.Split(value, "\t")
+	address, lists := parts[0], parts[1:]
+	if r.Method == http.MethodGet {
+		p.render(w, http.StatusOK, portalPage{Unsubscribe: token, UnsubscribeLists: lists, Message: "Do you want to unsubscribe " + address + "?"})
+		return
+	}
+	if err := p.subscriptions.unsubscribe(address, lists); err != nil {
+		logger.error("Couldn't unsubscribe " + address)
+		logger.error(err)
+		p.render(w, http.StatusInternalServerError, portalPage{Error: "Something went wrong, please try again later."})
+		return
+	}
+	logger.info(fmt.Sprintf("Unsubscribed %v from %v", address, lists))
+	p.render(w, http.StatusOK, portalPage{Message: "You were unsubscribed."})
+}
+
+func (p *Portal) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", p.handleIndex)
+	mux.HandleFunc("POST /login", p.handleLogin)
+	mux.HandleFunc("GET /auth", p.handleAuth)
+	mux.HandleFunc("POST /auth", p.handleAuth)
+	mux.HandleFunc("POST /save", p.handleSave)
+	mux.HandleFunc("POST /logout", p.handleLogout)
+	mux.HandleFunc("GET /unsubscribe", p.handleUnsubscribe)
+	mux.HandleFunc("POST /unsubscribe", p.handleUnsubscribe)
+	return mux
+}
+
+// serve starts the portal in the background
+func (p *Portal) serve() {
+	server := &http.Server{
+		Addr: p.settings().Listen,
+		Handler: p.handler(),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+	logger.info("Starting portal on " + p.settings().Listen + " ...")
+	go func() {
+		err := server.ListenAndServe()
+		logger.error("Portal stopped")
+		logger.error(err)
+	}()
+}
+
+var portalTemplate = template.Must(template.New("portal").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>WidNotifier</title>
+<style>
+body { font-family: sans-serif; max-width: 40rem; margin: 2rem auto; padding: 0 1rem; }
+fieldset { margin-bottom: 1rem; }
+label { display: block; margin: .4rem 0; }
+input[type=text], input[type=email], input[type=number], textarea { width: 100%; box-sizing: border-box; }
+.message { background: #e6f4e6; padding: .5rem; }
+.error { background: #fbe3e3; padding: .5rem; }
+</style>
+</head>
+<body>
+<h1>WidNotifier</h1>
+{{ if .Message }}<p class="message">{{ .Message }}</p>{{ end }}
+{{ if .Error }}<p class="error">{{ .Error }}</p>{{ end }}
+{{ if .Login }}
+<form method="post" action="/auth?token={{ .Login }}">
+<button type="submit">Log in</button>
+</form>
+{{ else if .Unsubscribe }}
+<form method="post" action="/unsubscribe?token={{ .Unsubscribe }}">
+{{ if .UnsubscribeLists }}<p>Lists: {{ range $i, $l := .UnsubscribeLists }}{{ if $i }}, {{ end }}{{ $l }}{{ end }}</p>
+{{ else }}<p>You won't get any notices anymore.</p>{{ end }}
+<button type="submit">Unsubscribe</button>
+</form>
+{{ else if .Address }}
+<p>Logged in as {{ .Address }}</p>
+<form method="post" action="/save">
+<input type="hidden" name="csrf" value="{{ .Csrf }}">
+<fieldset>
+<legend>Lists</legend>
+{{ range .Lists }}<label><input type="checkbox" name="list" value="{{ .Name }}"{{ if .Subscribed }} checked{{ end }}> {{ .Name }}</label>
+{{ else }}<p>There are no lists.</p>{{ end }}
+</fieldset>
+<fieldset>
+<legend>Personal filter</legend>
+<p>Only notices of your lists that match all of the following are sent to you. Leave empty to get all notices of your lists.</p>
+<label>Product names contain (comma-separated) <input type="text" name="products" value="{{ .Products }}"></label>
+<label>Title contains (comma-separated) <input type="text" name="titles" value="{{ .Titles }}"></label>
+<label>Minimum basescore (0 - 100) <input type="number" name="min_basescore" min="0" max="100" value="{{ .MinBasescore }}"></label>
+<label>Expression <textarea name="expression" rows="2">{{ .Expression }}</textarea></label>
+<label><input type="checkbox" name="revisions" value="1"{{ if .Revisions }} checked{{ end }}> Also send updated notices that match the filter</label>
+</fieldset>
+<button type="submit">Save</button>
+</form>
+<form method="post" action="/logout">
+<input type="hidden" name="csrf" value="{{ .Csrf }}">
+<p><button type="submit">Log out</button></p>
+</form>
+{{ else }}
+<form method="post" action="/login">
+<label>E-mail address <input type="email" name="address" required></label>
+<button type="submit">Send login link</button>
+</form>
+{{ end }}
+</body>
+</html>
+`))
@@ -0,0 +1,47 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPortalLoginLinkIsUsedOnce(t *testing.T) {
+	config := NewConfig()
+	config.Portal.BaseUrl = "https://wid.example.org"
+	config.Portal.Secret = "0123456789abcdef0123456789abcdef"
+	handler := NewPortal(config, nil, nil).handler()
+	token := signToken(config.Portal.Secret, "login", "r@example.org", time.Now().Add(PORTAL_LOGIN_LINK_VALIDITY))
+	expired := signToken(config.Portal.Secret, "login", "r@example.org", time.Now().Add(-time.Minute))
+	request := func(method string, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, "/auth?token=" + url.QueryEscape(token), nil))
+		return w
+	}
+	loggedIn := func(w *httptest.ResponseRecorder) bool {
+		return w.Code == http.StatusSeeOther && strings.Contains(w.Header().Get("Set-Cookie"), PORTAL_SESSION_COOKIE + "=")
+	}
+	// opening the link (e.g. by a mail scanner) only asks for a confirmation
+	for i := 0; i < 2; i++ {
+		if w := request(http.MethodGet, token); w.Code != http.StatusOK || w.Header().Get("Set-Cookie") != "" || !strings.Contains(w.Body.String(), `action="/auth?token=`) {
+			t.Fatalf("GET: got %v %v, want the confirmation", w.Code, w.Header())
+		}
+	}
+	if w := request(http.MethodPost, token); !loggedIn(w) {
+		t.Fatalf("POST: got %v %v, want a session", w.Code, w.Header())
+	}
+	if w := request(http.MethodPost, token); w.Code != http.StatusForbidden || loggedIn(w) {
+		t.Errorf("POST again: got %v %v, want forbidden", w.Code, w.Header())
+	}
+	if w := request(http.MethodGet, token); w.Code != http.StatusForbidden {
+		t.Errorf("GET after use: got %v, want forbidden", w.Code)
+	}
+	if w := request(http.MethodPost, expired); w.Code != http.StatusForbidden {
+		t.Errorf("expired: got %v, want forbidden", w.Code)
+	}
+}
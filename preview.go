@@ -0,0 +1,162 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+const PREVIEW_RECIPIENT = "recipient@example.org"
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// sampleNotices returns notices with all fields set, for previews
+func sampleNotices() []WidNotice {
+	published := time.Now().Truncate(time.Minute)
+	return []WidNotice{
+		{
+			Uuid: "00000000-0000-0000-0000-000000000001",
+			Name: "WID-SEC-W-0000-0001",
+			Title: "Beispielprodukt: Mehrere Schwachstellen ermöglichen Codeausführung",
+			Published: published.Add(-time.Hour),
+			Classification: "hoch",
+			Basescore: 75,
+			Status: "NEU",
+			ProductNames: []string{"Beispielprodukt < 2.4.1", "Open Source Beispiel"},
+			Cves: []string{"CVE-2026-0001", "CVE-2026-0002"},
+			NoPatch: "false",
+			ApiEndpointId: "bund",
+			PortalUrl: "https://wid.cert-bund.de/portal/wid/securityadvisory?name=WID-SEC-0000-0001",
+			Event: "new",
+			CveDetails: []CveDetails{
+				{Id: "CVE-2026-0001", Kev: true, KevDateAdded: "2026-01-01", Epss: 0.42, EpssPercentile: 0.97, CvssVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", CvssScore: 9.8},
+				{Id: "CVE-2026-0002"},
+			},
+			Kev: true,
+			MaxEpss: 0.42,
+			MatchedAssets: []Asset{{Name: "Beispielprodukt", Version: "2.3.0"}},
+		},
+		{
+			Uuid: "00000000-0000-0000-0000-000000000002",
+			Name: "WID-SEC-W-0000-0002",
+			Title: "Beispielserver: Schwachstelle ermöglicht Denial of Service",
+			Published: published.Add(-time.Hour * 24),
+			Classification: "kritisch",
+			Basescore: -1,
+			Status: "UPDATE",
+			ProductNames: []string{"Beispielserver"},
+			Cves: []string{"CVE-2026-0003"},
+			NoPatch: "true",
+			ApiEndpointId: "bay",
+			PortalUrl: "https://wid.lsi.bayern.de/portal/wid/securityadvisory?name=WID-SEC-0000-0002",
+			Event: "changed",
+			Changes: []FieldChange{
+				{Field: "classification", Old: "hoch", New: "kritisch"},
+				{Field: "cves", Old: "", New: "CVE-2026-0003", Added: []string{"CVE-2026-0003"}},
+			},
+		},
+	}
+}
+
+type previewCase struct {
+	name string
+	lists []string // n.Lists, selects the list template
+	language string
+}
+
+// previewSourceNotices returns the notices of the last EXPLAIN_PERIOD from
+// the enabled sources (or their recorded responses, see replay.go)
+func previewSourceNotices(config Config) ([]WidNotice, error) {
+	client, err := NewHttpClient(config.HttpConfiguration)
+	if err != nil { return nil, err }
+	notices := recentNotices(enabledSources(config), client)
+	enricher := NewEnricher(config.Enrichment)
+	enricher.refresh(client)
+	enricher.enrich(notices)
+	return notices, nil
+}
+
+// preview renders the templates against the notices and prints the mails,
+// or writes them to outDir. noticesFilePath "" uses the sample notices,
+// "sources" queries the enabled sources. All templates are rendered in all
+// languages to find errors. Returns false if a template failed.
+func preview(config Config, noticesFilePath string, outDir string) bool {
+	templates, err := NewMailTemplates(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Template error: %v\n", err)
+		return false
+	}
+	notices := sampleNotices()
+	if noticesFilePath == "sources" {
+		notices, err = previewSourceNotices(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't query the sources: %v\n", err)
+			return false
+		}
+		if len(notices) < 1 {
+			fmt.Fprintln(os.Stderr, "The sources returned no notices, using the sample notices")
+			notices = sampleNotices()
+		}
+	} else if noticesFilePath != "" {
+		notices, err = readNoticesFile(noticesFilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't read notices from %v: %v\n", noticesFilePath, err)
+			return false
+		}
+		for i := range notices {
+			if notices[i].Event == "" {
+				notices[i].Event = "new"
+			}
+		}
+	}
+	// the mails of the global template and of lists with their own
+	// template or language are shown
+	shown := []previewCase{{"global", []string{}, ""}}
+	cases := []previewCase{}
+	for _, language := range templateLanguages {
+		cases = append(cases, previewCase{"global", []string{}, language})
+	}
+	for _, l := range *config.Lists {
+		if l.Template != nil || l.Language != "" {
+			shown = append(shown, previewCase{"list " + l.Name, []string{l.Name}, ""})
+		}
+		for _, language := range templateLanguages {
+			cases = append(cases, previewCase{"list " + l.Name, []string{l.Name}, language})
+		}
+	}
+	ok := true
+	errors := map[string]bool{}
+	for _, c := range append(cases, shown...) {
+		for _, n := range notices {
+			n.Lists = c.lists
+			t := templates.forNotice(&n, c.language)
+			mc, err := t.generate(TemplateData{&n, Version})
+			if err != nil {
+				if !errors[err.Error()] {
+					fmt.Fprintf(os.Stderr, "Template error: %v\n", err)
+					errors[err.Error()] = true
+				}
+				ok = false
+				continue
+			}
+			if c.language != "" {
+				continue // only checked
+			}
+			mail := mc.serializeValidMail(config.SmtpConfiguration.From, PREVIEW_RECIPIENT)
+			if outDir != "" {
+				f := filepath.Join(outDir, unsafeFileNameChars.ReplaceAllString(t.Name + "_" + n.Name, "_") + ".eml")
+				if err := os.WriteFile(f, mail, 0640); err != nil {
+					fmt.Fprintf(os.Stderr, "Couldn't write %v: %v\n", f, err)
+					ok = false
+				}
+				continue
+			}
+			fmt.Printf("==> %v (%v, %v) <==\n%s\n\n", t.Name, c.name, n.Name, mail)
+		}
+	}
+	return ok
+}
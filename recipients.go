@@ -0,0 +1,117 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Recipients with their own preferences. Lists act as groups: recipients
+// get the notices of their lists, refined by their own filters.
+
+type RecipientChannel struct {
+	Type string `json:"type"` // "mail" or "webhook"
+	// webhook
+	Url string `json:"url"`
+	UrlFile string `json:"url_file"`
+	UrlCommand string `json:"url_command"`
+	Token string `json:"token"` // sent as bearer token
+	TokenFile string `json:"token_file"`
+	TokenCommand string `json:"token_command"`
+	// "markdown", "slack" (mrkdwn) or "text", "" = markdown for Markdown
+	// templates, text otherwise
+	Format string `json:"format"`
+}
+
+type Recipient struct {
+	Address string `json:"address"` // e-mail address, identifies the recipient
+	Lists []string `json:"lists"` // names of the lists the recipient gets notices from
+	// Filters refine the notices of the lists. Recipients without lists get
+	// all notices matching these filters.
+	Filter []Filter `json:"filter"`
+	Exclude []Filter `json:"exclude"`
+	Channels []RecipientChannel `json:"channels"` // empty = mail
+	Digest bool `json:"digest"` // one mail per cycle instead of one per notice
+	Language string `json:"language"` // of the default templates, "" = of the lists or the global template
+}
+
+func (r Recipient) channels() []RecipientChannel {
+	if len(r.Channels) < 1 {
+		return []RecipientChannel{{Type: "mail"}}
+	}
+	return r.Channels
+}
+
+// selectNotices returns the notices for this recipient, each notice once
+func (r Recipient) selectNotices(listNotices map[string][]WidNotice, allNotices []WidNotice) []WidNotice {
+	candidates := []WidNotice{}
+	if len(r.Lists) > 0 {
+		for _, name := range r.Lists {
+			for _, n := range listNotices[name] {
+				i := slices.IndexFunc(candidates, func(c WidNotice) bool { return c.Uuid == n.Uuid })
+				if i < 0 {
+					n.Lists = []string{name}
+					candidates = append(candidates, n)
+				} else {
+					candidates[i].Lists = append(candidates[i].Lists, name)
+				}
+			}
+		}
+	} else {
+		candidates = allNotices
+	}
+	selected := []WidNotice{}
+	for _, n := range candidates {
+		if len(r.Filter) > 0 && !slices.ContainsFunc(r.Filter, func(f Filter) bool { return f.mismatch(&n) == "" }) {
+			continue
+		}
+		if slices.ContainsFunc(r.Exclude, func(f Filter) bool { return f.excludes(&n) }) {
+			logger.debug(fmt.Sprintf("Notice %v left out for recipient %v: excluded", n.Name, r.Address))
+			continue
+		}
+		selected = append(selected, n)
+	}
+	return selected
+}
+
+// allRecipients returns the recipients of the registry and of the lists,
+// merged with the subscriptions (can be nil), sorted by address. Addresses
+// in the recipients of a list are treated like recipients that have this
+// list in their lists.
+func allRecipients(config Config, subscriptions map[string]Subscription) []Recipient {
+	recipients := slices.Clone(config.Recipients)
+	for _, l := range *config.Lists {
+		for _, address := range l.Recipients {
+			i := slices.IndexFunc(recipients, func(r Recipient) bool { return strings.EqualFold(r.Address, address) })
+			if i < 0 {
+				recipients = append(recipients, Recipient{Address: address, Lists: []string{l.Name}})
+			} else if !slices.Contains(recipients[i].Lists, l.Name) {
+				recipients[i].Lists = append(slices.Clone(recipients[i].Lists), l.Name)
+			}
+		}
+	}
+	merged := []Recipient{}
+	for _, r := range recipients {
+		sub, ok := subscriptions[strings.ToLower(r.Address)]
+		if ok {
+			r, ok = sub.apply(r)
+			if !ok { continue }
+		}
+		merged = append(merged, r)
+	}
+	for address, sub := range subscriptions {
+		// recipients that only exist in the subscriptions
+		if slices.ContainsFunc(recipients, func(r Recipient) bool { return strings.EqualFold(r.Address, address) }) {
+			continue
+		}
+		if r, ok := sub.apply(Recipient{Address: address}); ok {
+			merged = append(merged, r)
+		}
+	}
+	slices.SortFunc(merged, func(a Recipient, b Recipient) int {
+		return strings.Compare(a.Address, b.Address)
+	})
+	return merged
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/smtp"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The configuration is reloaded on SIGHUP (and when the file changes, if
+// reload_on_change is set). The main loop swaps the runtime between two
+// cycles, an invalid configuration is logged and the current one is kept.
+
+const CONFIG_WATCH_INTERVAL = time.Second * 5
+
+// Runtime is everything the main loop derives from the configuration
+type Runtime struct {
+	config Config
+	filterRegistry FilterRegistry
+	mailTemplates MailTemplates
+	mailAuth smtp.Auth
+	httpClient *HttpClient
+	sources []Source
+	enricher *Enricher
+}
+
+// NewRuntime creates the runtime for a checked configuration. The http
+// client and the enricher of previous (can be nil) are kept if their
+// settings didn't change.
+func NewRuntime(config Config, filterRegistry FilterRegistry, previous *Runtime) (*Runtime, error) {
+	rt := &Runtime{config: config, filterRegistry: filterRegistry}
+	var err error
+	logger.debug("Parsing mail templates ...")
+	rt.mailTemplates, err = NewMailTemplates(config)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse mail templates: %w", err)
+	}
+	if err := rt.mailTemplates.check(); err != nil {
+		return nil, fmt.Errorf("couldn't render mail templates: %w", err)
+	}
+	rt.mailAuth = smtp.PlainAuth(
+		"",
+		config.SmtpConfiguration.User,
+		config.SmtpConfiguration.Password,
+		config.SmtpConfiguration.ServerHost,
+	)
+	if previous != nil && reflect.DeepEqual(previous.config.HttpConfiguration, config.HttpConfiguration) {
+		rt.httpClient = previous.httpClient
+	} else {
+		rt.httpClient, err = NewHttpClient(config.HttpConfiguration)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create http client: %w", err)
+		}
+	}
+	rt.sources = enabledSources(config)
+	if previous != nil && reflect.DeepEqual(previous.config.Enrichment, config.Enrichment) {
+		rt.enricher = previous.enricher
+	} else {
+		rt.enricher = NewEnricher(config.Enrichment)
+	}
+	return rt, nil
+}
+
+// reloadConfig loads and checks the configuration file again and returns
+// the new runtime. The subscriptions (can be nil) get the new named filters.
+func reloadConfig(path string, current *Runtime, subscriptions *SubscriptionStore) (*Runtime, error) {
+	// loadConfig would create a new file
+	if _, err := os.Stat(path); err != nil { return nil, err }
+	config, err := loadConfig(path)
+	if err != nil { return nil, err }
+	for _, name := range keepRestartSettings(current.config, &config) {
+		logger.warn("Configuration: " + name + " changed, this requires a restart - keeping the current value")
+	}
+	filterRegistry, problems := validateConfig(&config)
+	if errors := logConfigProblems(problems); errors > 0 {
+		return nil, fmt.Errorf("configuration is invalid (%v errors)", errors)
+	}
+	rt, err := NewRuntime(config, filterRegistry, current)
+	if err != nil { return nil, err }
+	if subscriptions != nil {
+		if err := subscriptions.setRegistry(filterRegistry); err != nil { return nil, err }
+	}
+	return rt, nil
+}
+
+func jsonDiffers(a any, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return !bytes.Equal(x, y)
+}
+
+func byName[T any](items []T, name func(T) string) map[string]T {
+	m := map[string]T{}
+	for _, x := range items {
+		m[name(x)] = x
+	}
+	return m
+}
+
+// namedChanges summarizes the added, removed and changed items of a section
+func namedChanges[T any](section string, old map[string]T, new map[string]T) []string {
+	added, removed, changed := []string{}, []string{}, []string{}
+	for _, name := range slices.Sorted(maps.Keys(new)) {
+		if o, ok := old[name]; !ok {
+			added = append(added, name)
+		} else if jsonDiffers(o, new[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(old)) {
+		if _, ok := new[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	changes := []string{}
+	for _, c := range []struct{ what string; names []string }{{"added", added}, {"removed", removed}, {"changed", changed}} {
+		if len(c.names) > 0 {
+			changes = append(changes, section + " " + c.what + ": " + strings.Join(c.names, ", "))
+		}
+	}
+	return changes
+}
+
+// configChanges summarizes the differences between two configurations,
+// without the values of secrets
+func configChanges(old Config, new Config) []string {
+	changes := []string{}
+	if old.ApiFetchInterval != new.ApiFetchInterval {
+		changes = append(changes, fmt.Sprintf("api_fetch_interval: %v -> %v", old.ApiFetchInterval, new.ApiFetchInterval))
+	}
+	if old.LogLevel != new.LogLevel {
+		changes = append(changes, fmt.Sprintf("loglevel: %v -> %v", old.LogLevel, new.LogLevel))
+	}
+	if !slices.Equal(old.EnabledApiEndpoints, new.EnabledApiEndpoints) {
+		changes = append(changes, fmt.Sprintf("enabled_api_endpoints: [%v] -> [%v]", strings.Join(old.EnabledApiEndpoints, ", "), strings.Join(new.EnabledApiEndpoints, ", ")))
+	}
+	changes = append(changes, namedChanges("sources", byName(old.Sources, func(s SourceConfig) string { return s.Id }), byName(new.Sources, func(s SourceConfig) string { return s.Id }))...)
+	changes = append(changes, namedChanges("filters", old.Filters, new.Filters)...)
+	changes = append(changes, namedChanges("lists", byName(*old.Lists, func(l NotifyList) string { return l.Name }), byName(*new.Lists, func(l NotifyList) string { return l.Name }))...)
+	changes = append(changes, namedChanges("recipients", byName(old.Recipients, func(r Recipient) string { return r.Address }), byName(new.Recipients, func(r Recipient) string { return r.Address }))...)
+	for _, section := range []struct{ name string; old any; new any }{
+		{"http", old.HttpConfiguration, new.HttpConfiguration},
+		{"enrichment", old.Enrichment, new.Enrichment},
+		{"smtp", old.SmtpConfiguration, new.SmtpConfiguration},
+		{"portal", old.Portal, new.Portal},
+		{"template", old.Template, new.Template},
+	} {
+		if jsonDiffers(section.old, section.new) {
+			changes = append(changes, section.name + " changed")
+		}
+	}
+	return changes
+}
+
+// keepRestartSettings sets the settings that are only used before the main
+// loop back to their current values, so that e.g. no unsubscribe links are
+// sent for a portal that isn't running. Returns the names of the settings
+// that were changed.
+func keepRestartSettings(current Config, config *Config) []string {
+	changed := []string{}
+	for _, setting := range []struct{ name string; changed bool }{
+		{"datafile", config.PersistentDataFilePath != current.PersistentDataFilePath},
+		{"portal.listen", config.Portal.Listen != current.Portal.Listen},
+		{"portal.subscriptions_file", config.Portal.SubscriptionsFile != current.Portal.SubscriptionsFile},
+		{"reload_on_change", config.ReloadOnChange != current.ReloadOnChange},
+	} {
+		if setting.changed {
+			changed = append(changed, setting.name)
+		}
+	}
+	config.PersistentDataFilePath = current.PersistentDataFilePath
+	config.Portal.Listen = current.Portal.Listen
+	config.Portal.SubscriptionsFile = current.Portal.SubscriptionsFile
+	config.ReloadOnChange = current.ReloadOnChange
+	return changed
+}
+
+// watchConfigFile signals a reload when the file was changed and then
+// stayed the same for one interval
+func watchConfigFile(path string, reload chan<- os.Signal) {
+	stat := func() string {
+		info, err := os.Stat(path)
+		if err != nil { return "" }
+		return fmt.Sprint(info.ModTime().UnixNano(), info.Size())
+	}
+	last := stat()
+	pending := false
+	for range time.Tick(CONFIG_WATCH_INTERVAL) {
+		if current := stat(); current != last {
+			last = current
+			pending = true
+		} else if pending && current != "" {
+			pending = false
+			select {
+			case reload <- syscall.SIGHUP:
+			default: // a reload is pending anyway
+			}
+		}
+	}
+}
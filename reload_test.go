@@ -0,0 +1,33 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeepRestartSettings(t *testing.T) {
+	current := NewConfig()
+	config := NewConfig()
+	config.PersistentDataFilePath = "other.json"
+	config.ReloadOnChange = true
+	config.Portal.Listen = "127.0.0.1:8080"
+	config.Portal.BaseUrl = "https://wid.example.org"
+	config.ApiFetchInterval = 300
+	changed := keepRestartSettings(current, &config)
+	want := []string{"datafile", "portal.listen", "reload_on_change"}
+	if !slices.Equal(changed, want) {
+		t.Errorf("got changed settings %q, want %q", changed, want)
+	}
+	if config.PersistentDataFilePath != current.PersistentDataFilePath || config.ReloadOnChange || config.Portal.enabled() {
+		t.Errorf("restart settings weren't kept: %+v", config)
+	}
+	// everything else is applied
+	if config.Portal.BaseUrl != "https://wid.example.org" || config.ApiFetchInterval != 300 {
+		t.Errorf("other settings weren't applied: %+v", config)
+	}
+	if changed := keepRestartSettings(current, &config); len(changed) > 0 {
+		t.Errorf("got changed settings %q, want none", changed)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Record the raw HTTP responses of the sources to a directory and replay
+// them later, e.g. to test the whole pipeline offline. Responses for the
+// same url are numbered, replaying returns them in the same order and
+// repeats the last one. Other requests than GET (webhooks) are sent as usual.
+
+// recordingFileName only contains a hash of the url, urls can contain
+// secrets (e.g. tokens in query parameters)
+func recordingFileName(dir string, url string, n int) string {
+	h := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, fmt.Sprintf("%v.%04d.http", hex.EncodeToString(h[:16]), n))
+}
+
+type recordingTransport struct {
+	next http.RoundTripper
+	dir string
+	counters map[string]int // url : responses recorded
+	mutex sync.Mutex
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet { return res, err }
+	dump, err := httputil.DumpResponse(res, true) // the body stays readable
+	if err != nil { return res, err }
+	url := req.URL.String()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	f := recordingFileName(t.dir, url, t.counters[url])
+	t.counters[url]++
+	logger.debug("Recording response for " + url + " to " + f)
+	if err = os.WriteFile(f, dump, 0640); err != nil {
+		logger.error("Couldn't record response")
+		logger.error(err)
+	}
+	return res, nil
+}
+
+type replayTransport struct {
+	next http.RoundTripper // for other requests than GET
+	dir string
+	counters map[string]int // url : responses replayed
+	mutex sync.Mutex
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+	url := req.URL.String()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	n := t.counters[url]
+	dump, err := os.ReadFile(recordingFileName(t.dir, url, n))
+	if os.IsNotExist(err) && n > 0 {
+		// repeat the last response
+		n--
+		dump, err = os.ReadFile(recordingFileName(t.dir, url, n))
+	}
+	if os.IsNotExist(err) {
+		return nil, errors.New("no recorded response for " + url)
+	} else if err != nil {
+		return nil, err
+	}
+	t.counters[url] = n + 1
+	logger.debug(fmt.Sprintf("Replaying recorded response %v for %v", n, url))
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+}
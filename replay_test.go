@@ -0,0 +1,66 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	logger = NewLogger(0)
+	requests := 0
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		requests++
+		w.Header().Set("ETag", fmt.Sprintf(`"%v"`, requests))
+		fmt.Fprintf(w, "response %v for %v", requests, r.URL.Path)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	feed := server.URL + "/feed?token=secret"
+	hook := server.URL + "/hooks/T000/B000/secret"
+	// record
+	client, err := NewHttpClient(HttpSettings{RecordDir: dir})
+	if err != nil { t.Fatal(err) }
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.getIfModified(feed); err != nil { t.Fatal(err) }
+	}
+	if err := client.post(hook, "application/json", []byte("{}"), nil); err != nil { t.Fatal(err) }
+	entries, err := os.ReadDir(dir)
+	if err != nil { t.Fatal(err) }
+	if len(entries) != 2 {
+		t.Fatalf("got %v recordings, want 2 (the POST isn't recorded)", len(entries))
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), "secret") || strings.Contains(e.Name(), "feed") {
+			t.Errorf("the file name %v contains the url", e.Name())
+		}
+	}
+	// replay, without requests to the sources
+	client, err = NewHttpClient(HttpSettings{ReplayDir: dir})
+	if err != nil { t.Fatal(err) }
+	for _, want := range []string{"response 1 for /feed", "response 2 for /feed", "response 2 for /feed"} {
+		body, modified, err := client.getIfModified(feed)
+		if err != nil { t.Fatal(err) }
+		if string(body) != want || !modified {
+			t.Errorf("got %q (modified: %v), want %q", body, modified, want)
+		}
+	}
+	if _, err := client.get(server.URL + "/other"); err == nil {
+		t.Error("got a response for a url that wasn't recorded")
+	}
+	if err := client.post(hook, "application/json", []byte("{}"), nil); err != nil { t.Fatal(err) }
+	if requests != 2 || posts != 2 {
+		t.Errorf("got %v GET and %v POST requests, want 2 GET requests while recording and a POST each", requests, posts)
+	}
+}
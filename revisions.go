@@ -0,0 +1,157 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// fingerprints of notices that weren't seen for this long are forgotten
+const FINGERPRINT_RETENTION = time.Hour * 24 * 30
+
+// The fields of a notice that are compared to detect revisions
+type NoticeFingerprint struct {
+	Title string `json:"title"`
+	Classification string `json:"classification"`
+	Basescore int `json:"basescore"`
+	Status string `json:"status"`
+	ProductNames []string `json:"product_names"`
+	Cves []string `json:"cves"`
+	NoPatch string `json:"no_patch"`
+	Source string `json:"source"` // id of the source, "" for fingerprints of older versions
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type FieldChange struct {
+	Field string // e.g. "basescore", "no_patch"
+	Old string
+	New string
+	// list fields (product_names, cves)
+	Added []string
+	Removed []string
+}
+
+func (c FieldChange) String() string {
+	if c.Added != nil || c.Removed != nil {
+		changes := []string{}
+		for _, a := range c.Added {
+			changes = append(changes, "+" + a)
+		}
+		for _, r := range c.Removed {
+			changes = append(changes, "-" + r)
+		}
+		return c.Field + ": " + strings.Join(changes, ", ")
+	}
+	return c.Field + ": " + c.Old + " -> " + c.New
+}
+
+func NewNoticeFingerprint(n WidNotice) NoticeFingerprint {
+	return NoticeFingerprint{
+		Title: n.Title,
+		Classification: n.Classification,
+		Basescore: n.Basescore,
+		Status: n.Status,
+		ProductNames: n.ProductNames,
+		Cves: n.Cves,
+		NoPatch: n.NoPatch,
+		Source: n.ApiEndpointId,
+	}
+}
+
+// version identifies the state of a notice, regardless of when it was seen
+func (f NoticeFingerprint) version() string {
+	f.LastSeen = time.Time{}
+	f.Source = ""
+	data, _ := json.Marshal(f)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+func (f NoticeFingerprint) diff(n WidNotice) []FieldChange {
+	changes := []FieldChange{}
+	compare := func(field string, old string, new string) {
+		if old != new {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	compareList := func(field string, old []string, new []string) {
+		c := FieldChange{Field: field, Old: strings.Join(old, ", "), New: strings.Join(new, ", ")}
+		for _, x := range new {
+			if !slices.Contains(old, x) { c.Added = append(c.Added, x) }
+		}
+		for _, x := range old {
+			if !slices.Contains(new, x) { c.Removed = append(c.Removed, x) }
+		}
+		if c.Added != nil || c.Removed != nil {
+			changes = append(changes, c)
+		}
+	}
+	compare("title", f.Title, n.Title)
+	compare("classification", f.Classification, n.Classification)
+	compare("basescore", fmt.Sprint(f.Basescore), fmt.Sprint(n.Basescore))
+	compare("status", f.Status, n.Status)
+	compareList("product_names", f.ProductNames, n.ProductNames)
+	compareList("cves", f.Cves, n.Cves)
+	compare("no_patch", f.NoPatch, n.NoPatch)
+	return changes
+}
+
+// detectRevisions returns the notices published after since as "new" events
+// and known notices whose fields changed as "changed" events. The current
+// fingerprints are written to newFingerprints.
+func detectRevisions(notices []WidNotice, since time.Time, fingerprints map[string]NoticeFingerprint, newFingerprints map[string]NoticeFingerprint) []WidNotice {
+	events := []WidNotice{}
+	now := time.Now()
+	for _, n := range notices {
+		if fp, known := fingerprints[n.Uuid]; known {
+			if changes := fp.diff(n); len(changes) > 0 {
+				n.Event = "changed"
+				n.Changes = changes
+				events = append(events, n)
+			}
+		} else if n.Published.After(since) || n.Event == "new" {
+			// sources can mark notices as new themselves
+			n.Event = "new"
+			events = append(events, n)
+		} else if n.Event == "changed" {
+			// a revision reported by the source, but the previous state is
+			// unknown (e.g. the fingerprint was pruned) -> no changes
+			events = append(events, n)
+		}
+		// notices that were published before since but are unknown (e.g. after
+		// an update of wid-notifier) are only remembered
+		fp := NewNoticeFingerprint(n)
+		fp.LastSeen = now
+		newFingerprints[n.Uuid] = fp
+	}
+	return events
+}
+
+// refreshFingerprints marks the fingerprints of the given sources as seen.
+// Used for sources that answered without notices (e.g. not modified), their
+// notices are still listed. Fingerprints without source are refreshed too,
+// until their notice is seen again.
+func refreshFingerprints(fingerprints map[string]NoticeFingerprint, sourceIds []string, now time.Time) {
+	if len(sourceIds) < 1 { return }
+	for id, fp := range fingerprints {
+		if fp.Source == "" || slices.Contains(sourceIds, fp.Source) {
+			fp.LastSeen = now
+			fingerprints[id] = fp
+		}
+	}
+}
+
+// pruneFingerprints removes fingerprints that weren't seen for FINGERPRINT_RETENTION
+func pruneFingerprints(fingerprints map[string]NoticeFingerprint) {
+	for id, fp := range fingerprints {
+		if time.Since(fp.LastSeen) > FINGERPRINT_RETENTION {
+			delete(fingerprints, id)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectRevisions(t *testing.T) {
+	since := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	before := since.Add(-time.Hour * 24 * 60)
+	after := since.Add(time.Hour)
+	known := map[string]NoticeFingerprint{
+		"a": NewNoticeFingerprint(WidNotice{Uuid: "a", Title: "A", Basescore: 50, Cves: []string{"CVE-2026-1"}}),
+	}
+	tests := []struct {
+		name string
+		notice WidNotice
+		event string // "" = no event
+		changes []string
+	}{
+		{"new", WidNotice{Uuid: "b", Published: after}, "new", nil},
+		{"unknown and old", WidNotice{Uuid: "c", Published: before}, "", nil},
+		{"marked as new by the source", WidNotice{Uuid: "d", Published: before, Event: "new"}, "new", nil},
+		{"known, unchanged", WidNotice{Uuid: "a", Title: "A", Basescore: 50, Cves: []string{"CVE-2026-1"}, Published: before}, "", nil},
+		{"known, changed", WidNotice{Uuid: "a", Title: "A", Basescore: 70, Cves: []string{"CVE-2026-1", "CVE-2026-2"}, Published: before}, "changed", []string{"basescore: 50 -> 70", "cves: +CVE-2026-2"}},
+		// e.g. a CSAF document revised after its fingerprint was pruned
+		{"revision with unknown fingerprint", WidNotice{Uuid: "e", Published: before, Event: "changed"}, "changed", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprints := map[string]NoticeFingerprint{}
+			events := detectRevisions([]WidNotice{tt.notice}, since, known, fingerprints)
+			if _, ok := fingerprints[tt.notice.Uuid]; !ok {
+				t.Errorf("fingerprint of %v wasn't remembered", tt.notice.Uuid)
+			}
+			if tt.event == "" {
+				if len(events) > 0 {
+					t.Fatalf("got event %q, want none", events[0].Event)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Event != tt.event {
+				t.Fatalf("got %v, want one %q event", events, tt.event)
+			}
+			changes := []string{}
+			for _, c := range events[0].Changes {
+				changes = append(changes, c.String())
+			}
+			if len(changes) != len(tt.changes) {
+				t.Fatalf("got changes %q, want %q", changes, tt.changes)
+			}
+			for i := range changes {
+				if changes[i] != tt.changes[i] {
+					t.Errorf("got changes %q, want %q", changes, tt.changes)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneFingerprintsOfQuietSources(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-FINGERPRINT_RETENTION - time.Hour)
+	fingerprints := map[string]NoticeFingerprint{
+		"bund-1": {Source: "bund", LastSeen: old}, // bund answered "not modified"
+		"feed:1": {Source: "feed", LastSeen: old}, // feed returned notices, but not this one
+		"feed:2": {Source: "feed", LastSeen: now},
+		"csaf:1": {Source: "csaf", LastSeen: old}, // csaf failed
+		"legacy": {LastSeen: old},
+	}
+	refreshFingerprints(fingerprints, []string{"bund"}, now)
+	pruneFingerprints(fingerprints)
+	for id, want := range map[string]bool{"bund-1": true, "feed:1": false, "feed:2": true, "csaf:1": false, "legacy": true} {
+		if _, ok := fingerprints[id]; ok != want {
+			t.Errorf("%v: got kept = %v, want %v", id, ok, want)
+		}
+	}
+	// nothing is refreshed if all sources returned notices
+	fingerprints = map[string]NoticeFingerprint{"legacy": {LastSeen: old}}
+	refreshFingerprints(fingerprints, []string{}, now)
+	pruneFingerprints(fingerprints)
+	if len(fingerprints) > 0 {
+		t.Errorf("got %v, want nothing", fingerprints)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Secrets (SMTP password, portal secret, http proxy, webhook urls and
+// tokens) can be set in the configuration as a ${ENV} reference, or read
+// from a file or from the output of a command. The resolved secrets are
+// only kept in memory.
+
+const SECRET_COMMAND_TIMEOUT = time.Minute
+
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+var envSecretPattern = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// envSecret returns the value of the environment variable if the secret is
+// a single ${NAME} reference. Other values are used as they are, so secrets
+// can contain "${".
+func envSecret(s string) (string, error) {
+	m := envSecretPattern.FindStringSubmatch(s)
+	if m == nil { return s, nil }
+	value, ok := os.LookupEnv(m[1])
+	if !ok {
+		return "", errors.New("environment variable " + m[1] + " is not set")
+	}
+	return value, nil
+}
+
+// expandEnv replaces ${NAME} with the value of the environment variable
+func expandEnv(s string) (string, error) {
+	var err error
+	s = envReferencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2:len(ref) - 1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = errors.New("environment variable " + name + " is not set")
+		}
+		return value
+	})
+	return s, err
+}
+
+// readSecretFile reads the secret from the file. Relative paths are resolved
+// in $CREDENTIALS_DIRECTORY if set (LoadCredential= of systemd).
+func readSecretFile(path string) (string, error) {
+	path, err := expandEnv(path)
+	if err != nil { return "", err }
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil { return "", err }
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// runSecretCommand returns the output of the command, run with sh -c
+func runSecretCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), SECRET_COMMAND_TIMEOUT)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %v", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// secret resolves the secret field name at path from its value, <name>_file
+// or <name>_command, and replaces the value with the secret
+func (v *configValidator) secret(path string, name string, value *string, file string, command string) {
+	set := []string{}
+	for _, f := range [][2]string{{name, *value}, {name + "_file", file}, {name + "_command", command}} {
+		if f[1] != "" {
+			set = append(set, f[0])
+		}
+	}
+	if len(set) > 1 {
+		v.error(path + "." + set[1], "only one of %v can be set", strings.Join(set, ", "))
+		return
+	}
+	var err error
+	if file != "" {
+		*value, err = readSecretFile(file)
+		path += "." + name + "_file"
+	} else if command != "" {
+		*value, err = runSecretCommand(command)
+		path += "." + name + "_command"
+	} else {
+		*value, err = envSecret(*value)
+		path += "." + name
+	}
+	if err != nil {
+		v.error(path, "%v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// A Source provides notices, e.g. a WID API endpoint or a CSAF provider
+type Source interface {
+	sourceId() string
+	sourceName() string // for log messages
+	// getNotices returns the current notices of the source and the new cursor.
+	// Notices published before the cursor 'since' can be included, they are
+	// used to detect revisions.
+	getNotices(client *HttpClient, since time.Time) ([]WidNotice, time.Time, error)
+}
+
+// Additional sources besides the built-in API endpoints
+type SourceConfig struct {
+	Id string `json:"id"`
+	Type string `json:"type"` // "csaf", "feed" or "json"
+	Url string `json:"url"`
+	// csaf
+	VerifySignatures bool `json:"verify_signatures"`
+	Keyring string `json:"keyring"`
+	// feed, json
+	Classification string `json:"classification"` // assigned to all notices without classification
+	// json
+	ItemsPath string `json:"items_path"` // e.g. "data.advisories", "" = the document is the list
+	Fields map[string]string `json:"fields"` // notice field : path within an item
+	TimeFormat string `json:"time_format"` // Go time layout, default is RFC 3339
+	BasescoreFactor float64 `json:"basescore_factor"` // e.g. 10 for CVSS scores
+}
+
+// enabledSources returns the enabled API endpoints and additional sources
+func enabledSources(config Config) []Source {
+	sources := []Source{}
+	// filter out disabled api endpoints
+	for _, a := range apiEndpoints {
+		for _, b := range config.EnabledApiEndpoints {
+			if a.Id == b {
+				logger.debug("Endpoint '" + b + "' is enabled")
+				sources = append(sources, a)
+			}
+		}
+	}
+	// additional sources
+	for _, c := range config.Sources {
+		s, _ := NewSourceFromConfig(c) // already checked
+		logger.debug("Source '" + c.Id + "' (" + c.Type + ") is enabled")
+		sources = append(sources, s)
+	}
+	return sources
+}
+
+func NewSourceFromConfig(c SourceConfig) (Source, error) {
+	if c.Url == "" {
+		return nil, errors.New("source '" + c.Id + "' has no url")
+	}
+	switch c.Type {
+	case "csaf":
+		if c.VerifySignatures && c.Keyring == "" {
+			return nil, errors.New("source '" + c.Id + "' verifies signatures but has no keyring")
+		}
+		return CsafSource{
+			Id: c.Id,
+			MetadataUrl: c.Url,
+			VerifySignatures: c.VerifySignatures,
+			Keyring: c.Keyring,
+		}, nil
+	case "feed":
+		return FeedSource{
+			Id: c.Id,
+			Url: c.Url,
+			Classification: c.Classification,
+		}, nil
+	case "json":
+		for _, f := range []string{"title", "published"} {
+			if c.Fields[f] == "" {
+				return nil, errors.New("source '" + c.Id + "' has no path for the field '" + f + "'")
+			}
+		}
+		for f := range c.Fields {
+			if !slices.Contains(jsonSourceFields, f) {
+				return nil, errors.New("source '" + c.Id + "' has an unknown field '" + f + "'")
+			}
+		}
+		s := JsonSource{
+			Id: c.Id,
+			Url: c.Url,
+			Classification: c.Classification,
+			ItemsPath: c.ItemsPath,
+			Fields: c.Fields,
+			TimeFormat: c.TimeFormat,
+			BasescoreFactor: c.BasescoreFactor,
+		}
+		if s.TimeFormat == "" {
+			s.TimeFormat = time.RFC3339
+		}
+		if s.BasescoreFactor == 0 {
+			s.BasescoreFactor = 1
+		}
+		return s, nil
+	default:
+		return nil, errors.New("source '" + c.Id + "' has an unknown type '" + c.Type + "'")
+	}
+}
+
+// latestPublished returns the 'published' field of the last notice,
+// or since if there is no newer notice
+func latestPublished(notices []WidNotice, since time.Time) time.Time {
+	lastPublished := since
+	for _, n := range notices {
+		if n.Published.After(lastPublished) {
+			lastPublished = n.Published
+		}
+	}
+	return lastPublished
+}
+
+var cvePattern = regexp.MustCompile(`CVE-\d{4}-\d{4,}`)
+
+// findCves returns all distinct CVE ids mentioned in the given texts
+func findCves(texts ...string) []string {
+	cves := []string{}
+	for _, t := range texts {
+		for _, c := range cvePattern.FindAllString(t, -1) {
+			if !slices.Contains(cves, c) {
+				cves = append(cves, c)
+			}
+		}
+	}
+	return cves
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Subscription changes made by the recipients themselves (see portal.go),
+// stored in a separate file and merged with the configuration
+
+type Subscription struct {
+	Lists []string `json:"lists"` // lists the recipient subscribed to
+	Unsubscribed []string `json:"unsubscribed"` // lists the recipient left, including lists from the configuration
+	UnsubscribedAll bool `json:"unsubscribed_all"` // the recipient doesn't get any notices
+	// personal filters, replace the filters of the configuration if set
+	Filter []Filter `json:"filter"`
+}
+
+type SubscriptionData struct {
+	// {address (lower case): subscription, ...}
+	Subscriptions map[string]Subscription `json:"subscriptions"`
+}
+
+// SubscriptionStore is shared between the main loop and the portal
+type SubscriptionStore struct {
+	store DataStore
+	registry FilterRegistry
+	mutex sync.Mutex
+}
+
+func NewSubscriptionStore(path string, registry FilterRegistry) (*SubscriptionStore, error) {
+	s := &SubscriptionStore{
+		store: DataStore{filepath: path, data: SubscriptionData{Subscriptions: map[string]Subscription{}}, prettyJSON: true, fileMode: 0640},
+	}
+	if err := s.store.init(); err != nil { return nil, err }
+	if err := s.setRegistry(registry); err != nil { return nil, err }
+	return s, nil
+}
+
+// setRegistry compiles the filters of all subscriptions with the named
+// filters, e.g. of a reloaded configuration. Nothing is changed on errors.
+func (s *SubscriptionStore) setRegistry(registry FilterRegistry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	subscriptions := s.store.data.(SubscriptionData).Subscriptions
+	compiled := map[string]Subscription{}
+	for address, sub := range subscriptions {
+		sub.Filter = slices.Clone(sub.Filter)
+		for i := range sub.Filter {
+			if err := sub.Filter[i].compile(registry); err != nil {
+				return fmt.Errorf("subscription of %v, filter %v: %v", address, i, err)
+			}
+		}
+		compiled[address] = sub
+	}
+	maps.Copy(subscriptions, compiled)
+	s.registry = registry
+	return nil
+}
+
+// all returns a copy of all subscriptions
+func (s *SubscriptionStore) all() map[string]Subscription {
+	if s == nil { return nil }
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return maps.Clone(s.store.data.(SubscriptionData).Subscriptions)
+}
+
+func (s *SubscriptionStore) get(address string) Subscription {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.store.data.(SubscriptionData).Subscriptions[strings.ToLower(address)]
+}
+
+// set compiles the filters of the subscription and saves it
+func (s *SubscriptionStore) set(address string, sub Subscription) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range sub.Filter {
+		if err := sub.Filter[i].compile(s.registry); err != nil { return err }
+	}
+	s.store.data.(SubscriptionData).Subscriptions[strings.ToLower(address)] = sub
+	return s.store.save()
+}
+
+// unsubscribe removes the recipient from the lists, or from everything if
+// no lists are given
+func (s *SubscriptionStore) unsubscribe(address string, lists []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	address = strings.ToLower(address)
+	sub := s.store.data.(SubscriptionData).Subscriptions[address]
+	if len(lists) < 1 {
+		sub.UnsubscribedAll = true
+	}
+	for _, l := range lists {
+		sub.Lists = slices.DeleteFunc(slices.Clone(sub.Lists), func(x string) bool { return x == l })
+		if !slices.Contains(sub.Unsubscribed, l) {
+			sub.Unsubscribed = append(sub.Unsubscribed, l)
+		}
+	}
+	s.store.data.(SubscriptionData).Subscriptions[address] = sub
+	return s.store.save()
+}
+
+// apply merges the subscription into the recipient. ok is false if the
+// recipient left all lists.
+func (sub Subscription) apply(r Recipient) (Recipient, bool) {
+	if sub.UnsubscribedAll {
+		return r, false
+	}
+	// recipients of the configuration without lists get all notices
+	// matching their filters
+	allNotices := len(r.Lists) < 1 && len(r.Filter) > 0
+	lists := []string{}
+	for _, l := range slices.Concat(r.Lists, sub.Lists) {
+		if !slices.Contains(lists, l) && !slices.Contains(sub.Unsubscribed, l) {
+			lists = append(lists, l)
+		}
+	}
+	r.Lists = lists
+	if sub.Filter != nil {
+		r.Filter = sub.Filter
+	}
+	if len(r.Lists) < 1 && !allNotices {
+		return r, false
+	}
+	return r, true
+}
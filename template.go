@@ -4,34 +4,65 @@ package main
 
 import (
 	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
-const DEFAULT_SUBJECT_TEMPLATE = "[{{ .Classification }}] {{ .Title }}"
-const DEFAULT_BODY_TEMPLATE = `{{ if .Status }}[{{ .Status }}] {{ end }}{{ .Name }}
+// The default templates are translated with tr, see translations.go
+const DEFAULT_SUBJECT_TEMPLATE = "{{ if eq .Event \"changed\" }}[{{ tr \"Update\" }}] {{ end }}[{{ tr .Classification }}] {{ .Title }}"
+const DEFAULT_BODY_TEMPLATE = `{{ if .Status }}[{{ tr .Status }}] {{ end }}{{ .Name }}
 -> {{ .PortalUrl }}
 {{- if eq .NoPatch "true" }}
 
-No patch available!
+{{ tr "No patch available!" }}
 {{- end }}
+{{- if .Changes }}
+
+{{ tr "Changes" }}:{{ range $change := .Changes }}
+  - {{ $change }}
+{{- end }}{{ end }}
 {{ if gt .Basescore -1 }}
-Basescore: {{ .Basescore }}{{- end }}
-Published: {{ .Published }}
+{{ tr "Basescore" }}: {{ .Basescore }}{{- end }}
+{{ tr "Published" }}: {{ date .Published }}
 {{- if .ProductNames }}
 
-Affected Products:{{ range $product := .ProductNames }}
+{{ tr "Affected Products" }}:{{ range $product := .ProductNames }}
   - {{ $product }}
 {{- end }}{{ end }}
-{{- if .Cves }}
+{{- if .MatchedAssets }}
 
-Assigned CVEs:{{ range $cve := .Cves }}
-  - {{ $cve }} -> https://www.cve.org/CVERecord?id={{ $cve }}
+{{ tr "Affected Assets" }}:{{ range $asset := .MatchedAssets }}
+  - {{ $asset }}
 {{- end }}{{ end }}
+{{- if .Cves }}
 
+{{ tr "Assigned CVEs" }}:{{ if .CveDetails }}{{ range $cve := .CveDetails }}
+  - {{ $cve.Id }} -> {{ cveLink $cve.Id }}
+{{- if $cve.Kev }}
+    {{ tr "Known to be exploited (CISA KEV, added %v)" $cve.KevDateAdded }}{{ end }}
+{{- if $cve.Epss }}
+    {{ tr "EPSS: %.3f (percentile %.3f)" $cve.Epss $cve.EpssPercentile }}{{ end }}
+{{- if $cve.CvssVector }}
+    {{ $cve.CvssVector }}{{ end }}
+{{- end }}{{ else }}{{ range $cve := .Cves }}
+  - {{ $cve }} -> {{ cveLink $cve }}
+{{- end }}{{ end }}{{ end }}
 
-Sent by WidNotifier {{ .WidNotifierVersion }}
+
+{{ tr "Sent by WidNotifier %v" .WidNotifierVersion }}
 `
 
+// languages of the tr template function
+var templateLanguages = []string{"en", "de"}
+
 type TemplateData struct {
 	*WidNotice
 	WidNotifierVersion string
@@ -40,39 +71,296 @@ type TemplateData struct {
 type MailTemplateConfig struct {
 	SubjectTemplate string `json:"subject"`
 	BodyTemplate string `json:"body"`
+	HtmlTemplate string `json:"html"` // optional, adds a HTML part to the mails
+	// optional, replaces body and html: both are rendered from this
+	// Markdown template, as well as the text for chat webhooks
+	MarkdownTemplate string `json:"markdown"`
+	// alternatively, the templates can be read from files
+	SubjectFile string `json:"subject_file"`
+	BodyFile string `json:"body_file"`
+	HtmlFile string `json:"html_file"`
+	MarkdownFile string `json:"markdown_file"`
+	// the files in this directory can be included by their name,
+	// e.g. {{ template "footer.tmpl" . }}
+	Directory string `json:"directory"`
+	// default language, can be overridden per list and recipient
+	Language string `json:"language"`
+}
+
+// inherit returns the template config with the unset templates of parent
+func (tc MailTemplateConfig) inherit(parent MailTemplateConfig) MailTemplateConfig {
+	if tc.SubjectTemplate == "" && tc.SubjectFile == "" {
+		tc.SubjectTemplate, tc.SubjectFile = parent.SubjectTemplate, parent.SubjectFile
+	}
+	if tc.BodyTemplate == "" && tc.BodyFile == "" && tc.HtmlTemplate == "" && tc.HtmlFile == "" && tc.MarkdownTemplate == "" && tc.MarkdownFile == "" {
+		// a list template with its own body or html doesn't use the
+		// Markdown template of the parent
+		tc.MarkdownTemplate, tc.MarkdownFile = parent.MarkdownTemplate, parent.MarkdownFile
+	}
+	if tc.BodyTemplate == "" && tc.BodyFile == "" {
+		tc.BodyTemplate, tc.BodyFile = parent.BodyTemplate, parent.BodyFile
+	}
+	if tc.HtmlTemplate == "" && tc.HtmlFile == "" {
+		tc.HtmlTemplate, tc.HtmlFile = parent.HtmlTemplate, parent.HtmlFile
+	}
+	if tc.Directory == "" {
+		tc.Directory = parent.Directory
+	}
+	return tc
 }
 
 type MailTemplate struct {
+	Name string // identifies the template in the mail cache
 	SubjectTemplate template.Template
 	BodyTemplate template.Template
+	HtmlTemplate *htmltemplate.Template // nil = no HTML part
+	MarkdownTemplate *template.Template // replaces BodyTemplate and HtmlTemplate if set
+	sources map[string]string // template name : text, for errors
+}
+
+// TemplateError is a parse or execution error at a position in a template
+type TemplateError struct {
+	Template string // e.g. "global/en body" or "list Admins/de footer.tmpl"
+	Line int
+	Column int // 0 = unknown
+	Message string
+	Source string // the line of the error
+}
+
+func (e TemplateError) Error() string {
+	pos := fmt.Sprintf("line %v", e.Line)
+	if e.Column > 0 {
+		pos += fmt.Sprintf(", column %v", e.Column)
+	}
+	msg := e.Template + ", " + pos + ": " + e.Message
+	if e.Source != "" {
+		msg += "\n    " + e.Source
+		if e.Column > 0 {
+			msg += "\n    " + strings.Repeat(" ", e.Column - 1) + "^"
+		}
+	}
+	return msg
+}
+
+var templateErrorPattern = regexp.MustCompile(`(?s)^(?:html/)?template: ?([^:]+):(\d+)(?::(\d+))?: (.*)$`)
+
+// templateError adds the position in the template to errors of
+// text/template and html/template
+func (t MailTemplate) templateError(err error) error {
+	m := templateErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return fmt.Errorf("%v: %v", t.Name, err)
+	}
+	e := TemplateError{Template: t.Name + " " + m[1], Message: m[4]}
+	e.Line, _ = strconv.Atoi(m[2])
+	e.Column, _ = strconv.Atoi(m[3])
+	lines := strings.Split(t.sources[m[1]], "\n")
+	if e.Line > 0 && e.Line <= len(lines) {
+		e.Source = strings.ReplaceAll(lines[e.Line - 1], "\t", " ")
+	}
+	return e
 }
 
 func (t MailTemplate) generate(data TemplateData) (MailContent, error) {
 	c := MailContent{}
 	buffer := &bytes.Buffer{}
 	err := t.SubjectTemplate.Execute(buffer, data)
-	if err != nil { return c, err }
+	if err != nil { return c, t.templateError(err) }
 	c.Subject = buffer.String()
 	buffer.Truncate(0) // we can recycle our buffer
+	if t.MarkdownTemplate != nil {
+		err = t.MarkdownTemplate.Execute(buffer, data)
+		if err != nil { return c, t.templateError(err) }
+		c.Markdown = buffer.String()
+		c.Body = markdownToText(c.Markdown)
+		c.Html = markdownToHtml(c.Markdown)
+		return c, nil
+	}
 	err = t.BodyTemplate.Execute(buffer, data)
-	if err != nil { return c, err }
+	if err != nil { return c, t.templateError(err) }
 	c.Body = buffer.String()
+	if t.HtmlTemplate != nil {
+		buffer.Truncate(0)
+		err = t.HtmlTemplate.Execute(buffer, data)
+		if err != nil { return c, t.templateError(err) }
+		c.Html = buffer.String()
+	}
 	return c, nil
 }
 
-func NewTemplateFromTemplateConfig(tc MailTemplateConfig) MailTemplate {
-	subjectTemplate, err := template.New("subject").Parse(tc.SubjectTemplate)
-	if err != nil {
-		logger.error("Could not parse template")
-		panic(err)
+// readTemplate returns the template text, read from file if set
+func readTemplate(text string, file string) (string, error) {
+	if file == "" {
+		return text, nil
+	}
+	data, err := os.ReadFile(file)
+	return string(data), err
+}
+
+// readPartials reads all files in the directory (file name : content)
+func readPartials(dir string) (map[string]string, error) {
+	partials := map[string]string{}
+	if dir == "" {
+		return partials, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil { return nil, err }
+	for _, e := range entries {
+		if e.IsDir() { continue }
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil { return nil, err }
+		partials[e.Name()] = string(data)
+	}
+	return partials, nil
+}
+
+func NewTemplateFromTemplateConfig(name string, tc MailTemplateConfig, funcs template.FuncMap) (MailTemplate, error) {
+	t := MailTemplate{Name: name}
+	partials, err := readPartials(tc.Directory)
+	if err != nil { return t, err }
+	t.sources = maps.Clone(partials)
+	t.sources["subject"], err = readTemplate(tc.SubjectTemplate, tc.SubjectFile)
+	if err != nil { return t, err }
+	t.sources["body"], err = readTemplate(tc.BodyTemplate, tc.BodyFile)
+	if err != nil { return t, err }
+	t.sources["html"], err = readTemplate(tc.HtmlTemplate, tc.HtmlFile)
+	if err != nil { return t, err }
+	t.sources["markdown"], err = readTemplate(tc.MarkdownTemplate, tc.MarkdownFile)
+	if err != nil { return t, err }
+	parseText := func(name string) (*template.Template, error) {
+		tt := template.New(name).Funcs(funcs)
+		for n, p := range partials {
+			if _, err := tt.New(n).Parse(p); err != nil { return nil, t.templateError(err) }
+		}
+		if _, err := tt.Parse(t.sources[name]); err != nil { return nil, t.templateError(err) }
+		return tt, nil
+	}
+	subjectTemplate, err := parseText("subject")
+	if err != nil { return t, err }
+	bodyTemplate, err := parseText("body")
+	if err != nil { return t, err }
+	t.SubjectTemplate = *subjectTemplate
+	t.BodyTemplate = *bodyTemplate
+	if t.sources["markdown"] != "" {
+		t.MarkdownTemplate, err = parseText("markdown")
+		if err != nil { return t, err }
+	} else if t.sources["html"] != "" {
+		t.HtmlTemplate = htmltemplate.New("html").Funcs(htmltemplate.FuncMap(funcs))
+		for n, p := range partials {
+			if _, err := t.HtmlTemplate.New(n).Parse(p); err != nil { return t, t.templateError(err) }
+		}
+		if _, err := t.HtmlTemplate.Parse(t.sources["html"]); err != nil { return t, t.templateError(err) }
+	}
+	return t, nil
+}
+
+// MailTemplates holds the global template and those of the lists, for
+// each language
+type MailTemplates struct {
+	global map[string]MailTemplate // language : template
+	lists map[string]map[string]MailTemplate // list name : language : template
+	listLanguages map[string]string // list name : language
+	language string // default
+}
+
+func NewMailTemplates(config Config) (MailTemplates, error) {
+	global := config.Template
+	if global.SubjectTemplate == "" && global.SubjectFile == "" {
+		logger.debug("Using default template for mail subject")
+		global.SubjectTemplate = DEFAULT_SUBJECT_TEMPLATE
+	}
+	if global.BodyTemplate == "" && global.BodyFile == "" && global.MarkdownTemplate == "" && global.MarkdownFile == "" {
+		logger.debug("Using default template for mail body")
+		global.BodyTemplate = DEFAULT_BODY_TEMPLATE
+	}
+	templates := MailTemplates{
+		global: map[string]MailTemplate{},
+		lists: map[string]map[string]MailTemplate{},
+		listLanguages: map[string]string{},
+		language: global.Language,
+	}
+	// "" (the default) keeps the classification and status values of the
+	// API untranslated, as before translations were added
+	for _, language := range append([]string{""}, templateLanguages...) {
+		suffix := ""
+		if language != "" {
+			suffix = "/" + language
+		}
+		funcs := NewTemplateFuncs(config, language)
+		t, err := NewTemplateFromTemplateConfig("global" + suffix, global, funcs)
+		if err != nil { return templates, err }
+		templates.global[language] = t
+		for _, l := range *config.Lists {
+			if l.Template != nil {
+				if templates.lists[l.Name] == nil {
+					templates.lists[l.Name] = map[string]MailTemplate{}
+				}
+				t, err := NewTemplateFromTemplateConfig("list " + l.Name + suffix, l.Template.inherit(global), funcs)
+				if err != nil { return templates, err }
+				templates.lists[l.Name][language] = t
+			}
+		}
+	}
+	for _, l := range *config.Lists {
+		if l.Language != "" {
+			templates.listLanguages[l.Name] = l.Language
+		}
+	}
+	return templates, nil
+}
+
+// check renders the sample notices with all templates in all languages, so
+// that errors are found on startup and reload instead of when a notice is
+// sent
+func (t MailTemplates) check() error {
+	for _, n := range sampleNotices() {
+		for _, language := range append([]string{""}, templateLanguages...) {
+			if _, err := t.global[language].generate(TemplateData{&n, Version}); err != nil { return err }
+			for _, name := range slices.Sorted(maps.Keys(t.lists)) {
+				n.Lists = []string{name}
+				if _, err := t.lists[name][language].generate(TemplateData{&n, Version}); err != nil { return err }
+			}
+		}
+	}
+	return nil
+}
+
+// languageFor returns the language of the recipient, of the first list the
+// notice was selected from that has a language, or the default
+func (t MailTemplates) languageFor(n *WidNotice, language string) string {
+	for _, l := range n.Lists {
+		if language != "" { break }
+		language = t.listLanguages[l]
 	}
-	bodyTemplate, err := template.New("body").Parse(tc.BodyTemplate)
-	if err != nil {
-		logger.error("Could not parse template")
-		panic(err)
+	if language == "" {
+		language = t.language
 	}
-	return MailTemplate{
-		SubjectTemplate: *subjectTemplate,
-		BodyTemplate: *bodyTemplate,
+	return language
+}
+
+// digestLanguage returns the language of all parts of a digest: the language
+// of the recipient or the default, as the lists of the notices can have
+// different languages
+func (t MailTemplates) digestLanguage(language string) string {
+	if language == "" {
+		language = t.language
+	}
+	return language
+}
+
+// forNotice returns the template of the first list the notice was selected
+// from that has its own template, or the global template, in the language
+// of languageFor
+func (t MailTemplates) forNotice(n *WidNotice, language string) MailTemplate {
+	return t.inLanguage(n, t.languageFor(n, language))
+}
+
+// inLanguage is forNotice without the language of the lists, "" = untranslated
+func (t MailTemplates) inLanguage(n *WidNotice, language string) MailTemplate {
+	for _, l := range n.Lists {
+		if lt, ok := t.lists[l]; ok {
+			return lt[language]
+		}
 	}
+	return t.global[language]
 }
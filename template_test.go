@@ -0,0 +1,123 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateLanguages(t *testing.T) {
+	logger = NewLogger(0)
+	config := NewConfig()
+	config.Lists = &[]NotifyList{
+		{Name: "german", Language: "de", Template: &MailTemplateConfig{SubjectTemplate: "[DE] {{ .Title }}"}},
+		{Name: "english", Language: "en"},
+		{Name: "plain"},
+	}
+	tests := []struct {
+		name string
+		global string // language of the global template
+		recipient string
+		lists []string
+		language string
+		template string
+		digest string // template of a digest part
+	}{
+		{"untranslated", "", "", []string{"plain"}, "", "global", "global"},
+		{"global", "de", "", []string{"plain"}, "de", "global/de", "global/de"},
+		{"list", "de", "", []string{"english"}, "en", "global/en", "global/de"},
+		{"list without language is skipped", "", "", []string{"plain", "english"}, "en", "global/en", "global"},
+		{"first list with a language", "", "", []string{"german", "english"}, "de", "list german/de", "list german"},
+		{"recipient", "de", "en", []string{"german"}, "en", "list german/en", "list german/en"},
+		{"recipient without global language", "", "de", []string{"english"}, "de", "global/de", "global/de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Template.Language = tt.global
+			templates, err := NewMailTemplates(config)
+			if err != nil { t.Fatal(err) }
+			n := &WidNotice{Lists: tt.lists}
+			if got := templates.languageFor(n, tt.recipient); got != tt.language {
+				t.Errorf("got language %q, want %q", got, tt.language)
+			}
+			if got := templates.forNotice(n, tt.recipient).Name; got != tt.template {
+				t.Errorf("got template %q, want %q", got, tt.template)
+			}
+			// digests use the language of the recipient or the default for all parts
+			if got := templates.inLanguage(n, templates.digestLanguage(tt.recipient)).Name; got != tt.digest {
+				t.Errorf("got digest template %q, want %q", got, tt.digest)
+			}
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	logger = NewLogger(0)
+	badPartial := t.TempDir()
+	if err := os.WriteFile(filepath.Join(badPartial, "bad.tmpl"), []byte("ok\n{{ if }}"), 0644); err != nil { t.Fatal(err) }
+	footer := t.TempDir()
+	if err := os.WriteFile(filepath.Join(footer, "footer.tmpl"), []byte("ok\n  {{ .Nope }}"), 0644); err != nil { t.Fatal(err) }
+	tests := []struct {
+		name string
+		config MailTemplateConfig
+		want TemplateError
+	}{
+		{"parse error", MailTemplateConfig{BodyTemplate: "line\n{{ .Title }\n"},
+			TemplateError{"test body", 2, 0, `unexpected "}" in operand`, "{{ .Title }"}},
+		{"execution error", MailTemplateConfig{BodyTemplate: "x\n  {{ .Nope }} x"},
+			TemplateError{"test body", 2, 5, `executing "body" at <.Nope>: can't evaluate field Nope in type main.TemplateData`, "  {{ .Nope }} x"}},
+		{"execution error of a function", MailTemplateConfig{SubjectTemplate: "{{ index .Cves 5 }}"},
+			TemplateError{"test subject", 1, 3, `executing "subject" at <index .Cves 5>: error calling index: index out of range: 5`, "{{ index .Cves 5 }}"}},
+		{"parse error in a partial", MailTemplateConfig{Directory: badPartial},
+			TemplateError{"test bad.tmpl", 2, 0, "missing value for if", "{{ if }}"}},
+		{"execution error in a partial", MailTemplateConfig{BodyTemplate: `{{ template "footer.tmpl" . }}`, Directory: footer},
+			TemplateError{"test footer.tmpl", 2, 5, `executing "footer.tmpl" at <.Nope>: can't evaluate field Nope in type main.TemplateData`, "  {{ .Nope }}"}},
+		{"html execution error", MailTemplateConfig{HtmlTemplate: "<p>\n{{ .Nope }}"},
+			TemplateError{"test html", 2, 3, `executing "html" at <.Nope>: can't evaluate field Nope in type main.TemplateData`, "{{ .Nope }}"}},
+		{"html escape error", MailTemplateConfig{HtmlTemplate: "<p>\n<a href=\"{{ if .Kev }}/path/{{ else }}/search?q={{ end }}{{ .Title }}\">x</a>"},
+			TemplateError{"test html", 2, 60, "{{.Title}} appears in an ambiguous context within a URL", `<a href="{{ if .Kev }}/path/{{ else }}/search?q={{ end }}{{ .Title }}">x</a>`}},
+		{"markdown execution error", MailTemplateConfig{MarkdownTemplate: "# {{ .Title }}\n\n{{ .Nope }}"},
+			TemplateError{"test markdown", 3, 3, `executing "markdown" at <.Nope>: can't evaluate field Nope in type main.TemplateData`, "{{ .Nope }}"}},
+	}
+	funcs := NewTemplateFuncs(NewConfig(), "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := tt.config
+			if tc.SubjectTemplate == "" { tc.SubjectTemplate = "subject" }
+			if tc.BodyTemplate == "" { tc.BodyTemplate = "body" }
+			mt, err := NewTemplateFromTemplateConfig("test", tc, funcs)
+			if err == nil {
+				_, err = mt.generate(TemplateData{&sampleNotices()[0], Version})
+			}
+			var got TemplateError
+			if !errors.As(err, &got) {
+				t.Fatalf("got %v, want a TemplateError", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+	// other errors only get the name of the template
+	if err := (MailTemplate{Name: "test"}).templateError(errors.New("other")); err.Error() != "test: other" {
+		t.Errorf("got %q, want 'test: other'", err)
+	}
+}
+
+func TestRuntimeChecksTemplates(t *testing.T) {
+	logger = NewLogger(0)
+	config := NewConfig()
+	config.Lists = &[]NotifyList{{Name: "A", Template: &MailTemplateConfig{BodyTemplate: "{{ index .Cves 9 }}"}}}
+	_, err := NewRuntime(config, FilterRegistry{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "list A body") {
+		t.Errorf("got %v, want the execution error of the list template", err)
+	}
+	config.Lists = &[]NotifyList{{Name: "A"}}
+	if _, err := NewRuntime(config, FilterRegistry{}, nil); err != nil {
+		t.Errorf("got %v for the default templates", err)
+	}
+}
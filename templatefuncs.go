@@ -0,0 +1,125 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Functions that can be used in templates, e.g.
+//   Published: {{ date .Published }}
+//   {{ severityEmoji .Classification }} {{ truncate 60 .Title }}
+
+const TEMPLATE_TIMEZONE = "Europe/Berlin"
+
+type EndpointInfo struct {
+	Id string
+	Name string
+	Url string
+	PortalUrl string // of the WID portal, only API endpoints
+}
+
+var severityEmojis = map[string]string{
+	"kritisch": "🔴",
+	"hoch": "🟠",
+	"mittel": "🟡",
+	"niedrig": "🟢",
+}
+
+var severityColors = map[string]string{
+	"kritisch": "#d32f2f",
+	"hoch": "#f57c00",
+	"mittel": "#fbc02d",
+	"niedrig": "#388e3c",
+}
+
+var markdownSpecialChars = regexp.MustCompile("([\\\\`*_{}\\[\\]()#+\\-.!|<>~])")
+
+// wrapText breaks lines after at most width characters, at spaces
+func wrapText(width int, s string) string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && utf8.RuneCountInString(line) + 1 + utf8.RuneCountInString(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" { line += " " }
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isEmpty checks if a value is the zero value or an empty list
+func isEmpty(v any) bool {
+	if v == nil { return true }
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return r.Len() == 0
+	}
+	return r.IsZero()
+}
+
+func NewTemplateFuncs(config Config, language string) template.FuncMap {
+	location, err := time.LoadLocation(TEMPLATE_TIMEZONE)
+	if err != nil {
+		location = time.Local
+	}
+	endpoints := map[string]EndpointInfo{}
+	for _, e := range apiEndpoints {
+		endpoints[e.Id] = EndpointInfo{e.Id, e.Name, e.EndpointUrl, e.PortalUrl}
+	}
+	for _, s := range config.Sources {
+		endpoints[s.Id] = EndpointInfo{s.Id, s.Id, s.Url, ""}
+	}
+	return template.FuncMap{
+		// dates in Europe/Berlin
+		"date": func(t time.Time) string { return t.In(location).Format("02.01.2006 15:04") },
+		"formatDate": func(layout string, t time.Time) string { return t.In(location).Format(layout) },
+		// severity
+		"severityEmoji": func(classification string) string {
+			if e, ok := severityEmojis[strings.ToLower(classification)]; ok { return e }
+			return "⚪"
+		},
+		"severityColor": func(classification string) string {
+			if c, ok := severityColors[strings.ToLower(classification)]; ok { return c }
+			return "#757575"
+		},
+		// text
+		"join": func(sep string, list []string) string { return strings.Join(list, sep) },
+		"truncate": func(n int, s string) string {
+			if utf8.RuneCountInString(s) <= n { return s }
+			return string([]rune(s)[:max(n - 1, 0)]) + "…"
+		},
+		"wrap": wrapText,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"escapeMarkdown": func(s string) string { return markdownSpecialChars.ReplaceAllString(s, "\\$1") },
+		"default": func(def any, v any) any {
+			if isEmpty(v) { return def }
+			return v
+		},
+		// links
+		"cveLink": func(id string) string { return "https://www.cve.org/CVERecord?id=" + strings.ToUpper(id) },
+		"cweLink": func(id string) string {
+			return fmt.Sprintf("https://cwe.mitre.org/data/definitions/%v.html", strings.TrimPrefix(strings.ToUpper(id), "CWE-"))
+		},
+		// translation, see translations.go
+		"tr": func(text string, args ...any) string { return translate(language, text, args...) },
+		// metadata of the API endpoint or source of a notice
+		"endpoint": func(id string) EndpointInfo {
+			if e, ok := endpoints[id]; ok { return e }
+			return EndpointInfo{Id: id, Name: id}
+		},
+	}
+}
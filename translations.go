@@ -0,0 +1,52 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+)
+
+// Translations for the tr template function. The keys are the english
+// labels and the classification and status values of the API (german).
+// Unknown keys are returned unchanged, as are all keys without a language.
+
+var translations = map[string]map[string]string{
+	"en": {
+		// classifications
+		"kritisch": "critical",
+		"hoch": "high",
+		"mittel": "medium",
+		"niedrig": "low",
+		// status
+		"NEU": "new",
+		"UPDATE": "update",
+	},
+	"de": {
+		"Update": "Aktualisierung",
+		"No patch available!": "Kein Patch verfügbar!",
+		"Changes": "Änderungen",
+		"Basescore": "Basescore",
+		"Published": "Veröffentlicht",
+		"Affected Products": "Betroffene Produkte",
+		"Affected Assets": "Betroffene Systeme",
+		"Assigned CVEs": "Zugeordnete CVEs",
+		"Known to be exploited (CISA KEV, added %v)": "Wird bekanntermaßen ausgenutzt (CISA KEV, aufgenommen am %v)",
+		"EPSS: %.3f (percentile %.3f)": "EPSS: %.3f (Perzentil %.3f)",
+		"Sent by WidNotifier %v": "Gesendet von WidNotifier %v",
+		"%v new security notices": "%v neue Sicherheitshinweise",
+		// status
+		"NEU": "neu",
+		"UPDATE": "Aktualisierung",
+	},
+}
+
+// translate returns the text in the given language, formatted with args
+func translate(language string, text string, args ...any) string {
+	if t, ok := translations[language][text]; ok {
+		text = t
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
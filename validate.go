@@ -0,0 +1,316 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// A ConfigProblem is an error or warning at a JSON path of the
+// configuration, e.g. lists[2].filter[0].classification
+type ConfigProblem struct {
+	Path string
+	Message string
+	Warning bool
+}
+
+func (p ConfigProblem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// values the notices can have, to check filters
+var knownClassifications = []string{"niedrig", "mittel", "hoch", "kritisch"}
+var knownStatuses = []string{"NEU", "UPDATE"}
+var knownEvents = []string{"", "new", "changed", "all"}
+var knownChangedFields = []string{"title", "classification", "basescore", "status", "product_names", "cves", "no_patch"}
+
+type configValidator struct {
+	problems []ConfigProblem
+	classifications []string
+	sourceIds []string
+	enabledSourceIds []string
+	// only the WID API endpoints are enabled, so only the known
+	// classifications and statuses are possible
+	strict bool
+}
+
+func (v *configValidator) error(path string, format string, args ...any) {
+	v.problems = append(v.problems, ConfigProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *configValidator) warn(path string, format string, args ...any) {
+	v.problems = append(v.problems, ConfigProblem{Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// unknownValue reports a value that no notice has, as error if strict
+func (v *configValidator) unknownValue(path string, value string, known []string) {
+	msg := fmt.Sprintf("unknown value %q", value)
+	// e.g. "high" instead of "hoch"
+	for k, t := range translations["en"] {
+		if slices.Contains(known, k) && (strings.EqualFold(t, value) || strings.EqualFold(k, value)) {
+			msg += fmt.Sprintf(" (did you mean %q?)", k)
+			break
+		}
+	}
+	if v.strict {
+		v.error(path, "%v, expected one of %v", msg, strings.Join(known, ", "))
+	} else {
+		v.warn(path, "%v, the WID API uses %v", msg, strings.Join(known, ", "))
+	}
+}
+
+func (f Filter) hasCriteria() bool {
+	return f.Any || len(f.Ref) > 0 || len(f.TitleContains) > 0 || len(f.TitleRegex) > 0 ||
+		f.Classification != "" || f.MinBaseScore > 0 || f.Status != "" ||
+		len(f.ProductsContain) > 0 || len(f.ProductsRegex) > 0 || f.NoPatch != "" ||
+		f.ApiEndpointId != "" || len(f.Cves) > 0 || len(f.CveRegex) > 0 ||
+		f.CveYearMin > 0 || f.CveYearMax > 0 || f.CveWatchlist != "" || f.Kev != nil ||
+		f.MinEpss > 0 || f.Expression != "" || f.ChangedField != ""
+}
+
+// filter compiles the filter and checks its values
+func (v *configValidator) filter(path string, f *Filter, registry FilterRegistry) {
+	if err := f.compile(registry); err != nil {
+		v.error(path, "%v", err)
+	}
+	if f.Classification != "" && !slices.Contains(v.classifications, f.Classification) {
+		v.unknownValue(path + ".classification", f.Classification, v.classifications)
+	}
+	if f.Status != "" && !slices.Contains(knownStatuses, f.Status) {
+		v.unknownValue(path + ".status", f.Status, knownStatuses)
+	}
+	if f.NoPatch != "" && f.NoPatch != "true" && f.NoPatch != "false" {
+		v.error(path + ".no_patch", "unknown value %q, expected \"true\" or \"false\"", f.NoPatch)
+	}
+	if f.ApiEndpointId != "" {
+		if !slices.Contains(v.sourceIds, f.ApiEndpointId) {
+			v.error(path + ".api_endpoint", "unknown endpoint or source id %q, expected one of %v", f.ApiEndpointId, strings.Join(v.sourceIds, ", "))
+		} else if !slices.Contains(v.enabledSourceIds, f.ApiEndpointId) {
+			v.warn(path + ".api_endpoint", "endpoint %q is not enabled", f.ApiEndpointId)
+		}
+	}
+	if !slices.Contains(knownEvents, f.Event) {
+		v.error(path + ".event", "unknown value %q, expected \"new\", \"changed\" or \"all\"", f.Event)
+	}
+	if f.ChangedField != "" && !slices.Contains(knownChangedFields, f.ChangedField) {
+		v.error(path + ".changed_field", "unknown field %q, expected one of %v", f.ChangedField, strings.Join(knownChangedFields, ", "))
+	}
+	if f.MinBaseScore < 0 || f.MinBaseScore > 100 {
+		v.error(path + ".min_basescore", "%v is not between 0 and 100", f.MinBaseScore)
+	}
+	if f.MinEpss < 0 || f.MinEpss > 1 {
+		v.error(path + ".min_epss", "%v is not between 0 and 1", f.MinEpss)
+	}
+	if f.CveYearMin > 0 && f.CveYearMax > 0 && f.CveYearMin > f.CveYearMax {
+		v.error(path + ".cve_year_min", "%v is after cve_year_max %v", f.CveYearMin, f.CveYearMax)
+	}
+	if !f.hasCriteria() {
+		v.warn(path, "no criteria set, the filter doesn't match any notice (use \"any\": true to match all)")
+	} else if f.Any && (f.Classification != "" || f.Status != "" || len(f.TitleContains) > 0 || f.Expression != "") {
+		v.warn(path, "\"any\" is set, the other criteria are ignored")
+	}
+}
+
+// filterRegistry compiles the named filters
+func (v *configValidator) filterRegistry(filters map[string]Filter) FilterRegistry {
+	registry := FilterRegistry{}
+	for id, f := range filters {
+		registry[id] = &f
+	}
+	ids := slices.Sorted(maps.Keys(registry))
+	for _, id := range ids {
+		v.filter("filters." + id, registry[id], registry)
+	}
+	for _, id := range ids {
+		if err := registry.checkCycles(id, []string{}); err != nil {
+			v.error("filters." + id, "%v", err)
+			break
+		}
+	}
+	return registry
+}
+
+func (v *configValidator) recipient(path string, r *Recipient, registry FilterRegistry, lists []NotifyList) {
+	if !mailAddressIsValid(r.Address) {
+		v.error(path + ".address", "%q is not a valid e-mail address", r.Address)
+	}
+	if len(r.Lists) < 1 && len(r.Filter) < 1 {
+		v.error(path, "recipient %v has neither lists nor filters", r.Address)
+	}
+	for i, name := range r.Lists {
+		if !slices.ContainsFunc(lists, func(l NotifyList) bool { return l.Name == name }) {
+			v.error(fmt.Sprintf("%v.lists[%v]", path, i), "unknown list %q", name)
+		}
+	}
+	for i := range r.Filter {
+		v.filter(fmt.Sprintf("%v.filter[%v]", path, i), &r.Filter[i], registry)
+	}
+	for i := range r.Exclude {
+		v.filter(fmt.Sprintf("%v.exclude[%v]", path, i), &r.Exclude[i], registry)
+	}
+	for i, c := range r.Channels {
+		p := fmt.Sprintf("%v.channels[%v]", path, i)
+		if c.Type != "mail" && c.Type != "webhook" {
+			v.error(p + ".type", "unknown channel type %q, expected \"mail\" or \"webhook\"", c.Type)
+		}
+		if c.Type == "webhook" && c.Url == "" && c.UrlFile == "" && c.UrlCommand == "" {
+			v.error(p + ".url", "webhook channel without url")
+		}
+		if !slices.Contains([]string{"", "markdown", "slack", "text"}, c.Format) {
+			v.error(p + ".format", "unknown format %q, expected \"markdown\", \"slack\" or \"text\"", c.Format)
+		}
+	}
+	if r.Language != "" && !slices.Contains(templateLanguages, r.Language) {
+		v.error(path + ".language", "unsupported language %q", r.Language)
+	}
+}
+
+func (v *configValidator) list(path string, l *NotifyList, registry FilterRegistry) {
+	if l.Name == "" {
+		v.error(path + ".name", "the list has no name")
+	}
+	if len(l.Filter) < 1 {
+		v.error(path + ".filter", "no filter defined - at least [{\"any\": true}] should be configured")
+	}
+	for i := range l.Filter {
+		v.filter(fmt.Sprintf("%v.filter[%v]", path, i), &l.Filter[i], registry)
+	}
+	for i := range l.Exclude {
+		v.filter(fmt.Sprintf("%v.exclude[%v]", path, i), &l.Exclude[i], registry)
+	}
+	for i, r := range l.Recipients {
+		if !mailAddressIsValid(r) {
+			v.error(fmt.Sprintf("%v.recipients[%v]", path, i), "%q is not a valid e-mail address", r)
+		}
+	}
+	if l.Language != "" && !slices.Contains(templateLanguages, l.Language) {
+		v.error(path + ".language", "unsupported language %q", l.Language)
+	}
+	if l.Delivery != nil {
+		if err := l.Delivery.compile(); err != nil {
+			v.error(path + ".delivery", "%v", err)
+		}
+	}
+	if err := l.loadInventory(); err != nil {
+		v.error(path + ".inventory", "%v", err)
+	}
+}
+
+// validateConfig resolves the secrets, compiles the filters of the
+// configuration and checks all settings. Returns the registry of named
+// filters and all problems found.
+func validateConfig(config *Config) (FilterRegistry, []ConfigProblem) {
+	v := configValidator{classifications: slices.Clone(knownClassifications), strict: len(config.Sources) < 1}
+	// secrets
+	s := &config.SmtpConfiguration
+	v.secret("smtp", "password", &s.Password, s.PasswordFile, s.PasswordCommand)
+	v.secret("portal", "secret", &config.Portal.Secret, config.Portal.SecretFile, config.Portal.SecretCommand)
+	h := &config.HttpConfiguration
+	v.secret("http", "proxy", &h.Proxy, h.ProxyFile, h.ProxyCommand)
+	for i := range config.Recipients {
+		for j := range config.Recipients[i].Channels {
+			c := &config.Recipients[i].Channels[j]
+			p := fmt.Sprintf("recipients[%v].channels[%v]", i, j)
+			v.secret(p, "url", &c.Url, c.UrlFile, c.UrlCommand)
+			v.secret(p, "token", &c.Token, c.TokenFile, c.TokenCommand)
+		}
+	}
+	if config.ApiFetchInterval <= 0 {
+		v.error("api_fetch_interval", "%v is not a positive number of seconds", config.ApiFetchInterval)
+	} else if config.ApiFetchInterval < 60 {
+		v.warn("api_fetch_interval", "%v seconds is very short, the sources are queried in every cycle", config.ApiFetchInterval)
+	}
+	// sources
+	for _, e := range apiEndpoints {
+		v.sourceIds = append(v.sourceIds, e.Id)
+	}
+	for i, id := range config.EnabledApiEndpoints {
+		path := fmt.Sprintf("enabled_api_endpoints[%v]", i)
+		if !slices.ContainsFunc(apiEndpoints, func(e ApiEndpoint) bool { return e.Id == id }) {
+			v.error(path, "unknown endpoint %q, expected one of %v", id, strings.Join(v.sourceIds, ", "))
+		} else if slices.Contains(v.enabledSourceIds, id) {
+			v.warn(path, "endpoint %q is enabled more than once", id)
+		} else {
+			v.enabledSourceIds = append(v.enabledSourceIds, id)
+		}
+	}
+	for i, s := range config.Sources {
+		path := fmt.Sprintf("sources[%v]", i)
+		if s.Id == "" || slices.Contains(v.sourceIds, s.Id) {
+			v.error(path + ".id", "source id %q is empty or not unique", s.Id)
+		}
+		v.sourceIds = append(v.sourceIds, s.Id)
+		v.enabledSourceIds = append(v.enabledSourceIds, s.Id)
+		if _, err := NewSourceFromConfig(s); err != nil {
+			v.error(path, "%v", err)
+		}
+		if s.Classification != "" && !slices.Contains(v.classifications, s.Classification) {
+			v.classifications = append(v.classifications, s.Classification)
+		}
+	}
+	if len(v.enabledSourceIds) < 1 {
+		v.error("enabled_api_endpoints", "no API endpoints or sources are enabled")
+	}
+	if config.PersistentDataFilePath == "" {
+		v.error("datafile", "no path set")
+	}
+	if config.LogLevel < 0 || config.LogLevel > 3 {
+		v.warn("loglevel", "%v is not between 0 (errors) and 3 (debug)", config.LogLevel)
+	}
+	// filters, lists and recipients
+	registry := v.filterRegistry(config.Filters)
+	if len(*config.Lists) < 1 && len(config.Recipients) < 1 {
+		v.error("lists", "no lists or recipients are configured")
+	}
+	for i := range *config.Lists {
+		l := &(*config.Lists)[i]
+		path := fmt.Sprintf("lists[%v]", i)
+		if slices.ContainsFunc((*config.Lists)[:i], func(x NotifyList) bool { return x.Name == l.Name }) {
+			v.error(path + ".name", "list name %q is not unique", l.Name)
+		}
+		v.list(path, l, registry)
+		if len(l.Recipients) < 1 && !config.Portal.enabled() && !slices.ContainsFunc(config.Recipients, func(r Recipient) bool {
+			return slices.Contains(r.Lists, l.Name)
+		}) {
+			v.warn(path + ".recipients", "list %q has no recipients", l.Name)
+		}
+	}
+	addresses := []string{}
+	for i := range config.Recipients {
+		r := &config.Recipients[i]
+		path := fmt.Sprintf("recipients[%v]", i)
+		v.recipient(path, r, registry, *config.Lists)
+		if slices.Contains(addresses, strings.ToLower(r.Address)) {
+			v.error(path + ".address", "recipient %v is configured more than once", r.Address)
+		}
+		addresses = append(addresses, strings.ToLower(r.Address))
+	}
+	// mail
+	if !mailAddressIsValid(config.SmtpConfiguration.From) {
+		v.error("smtp.from", "%q is not a valid e-mail address", config.SmtpConfiguration.From)
+	}
+	if config.SmtpConfiguration.ServerHost == "" {
+		v.error("smtp.host", "no host set")
+	}
+	if config.SmtpConfiguration.ServerPort < 1 || config.SmtpConfiguration.ServerPort > 65535 {
+		v.error("smtp.port", "%v is not a valid port", config.SmtpConfiguration.ServerPort)
+	}
+	if config.SmtpConfiguration.Password == NewConfig().SmtpConfiguration.Password {
+		v.warn("smtp.password", "the password of the initial configuration is still set")
+	}
+	if config.Template.Language != "" && !slices.Contains(templateLanguages, config.Template.Language) {
+		v.error("template.language", "unsupported language %q", config.Template.Language)
+	}
+	if config.Portal.enabled() {
+		if err := config.Portal.check(); err != nil {
+			v.error("portal", "%v", err)
+		}
+	}
+	return registry, v.problems
+}
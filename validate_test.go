@@ -0,0 +1,185 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig returns a configuration without problems
+func validConfig() Config {
+	c := NewConfig()
+	c.SmtpConfiguration.Password = "secret"
+	c.Lists = &[]NotifyList{
+		{Name: "A", Recipients: []string{"a@example.org"}, Filter: []Filter{{Any: true}}},
+		{Name: "B", Recipients: []string{"b@example.org"}, Filter: []Filter{{Classification: "hoch"}}},
+		{Name: "C", Recipients: []string{"c@example.org"}, Filter: []Filter{{Classification: "kritisch"}}},
+	}
+	return c
+}
+
+func TestValidateConfig(t *testing.T) {
+	logger = NewLogger(0)
+	t.Setenv("WID_TEST_SECRET", "from env")
+	type problem struct {
+		path string
+		warning bool
+		message string // prefix
+	}
+	webhook := func(c RecipientChannel) []Recipient {
+		return []Recipient{{Address: "w@example.org", Filter: []Filter{{Any: true}}, Channels: []RecipientChannel{c}}}
+	}
+	tests := []struct {
+		name string
+		change func(c *Config)
+		want []problem
+	}{
+		{"valid", func(c *Config) {}, nil},
+		// values the WID API doesn't use are errors, unless other sources are configured
+		{"unknown classification", func(c *Config) { (*c.Lists)[2].Filter[0].Classification = "high" },
+			[]problem{{"lists[2].filter[0].classification", false, `unknown value "high" (did you mean "hoch"?), expected one of niedrig, mittel, hoch, kritisch`}}},
+		{"unknown classification with other sources", func(c *Config) {
+			c.Sources = []SourceConfig{{Id: "rss", Type: "feed", Url: "https://example.org/feed.xml"}}
+			(*c.Lists)[2].Filter[0].Classification = "high"
+		}, []problem{{"lists[2].filter[0].classification", true, `unknown value "high" (did you mean "hoch"?), the WID API uses`}}},
+		{"classification of a source", func(c *Config) {
+			c.Sources = []SourceConfig{{Id: "rss", Type: "feed", Url: "https://example.org/feed.xml", Classification: "high"}}
+			(*c.Lists)[2].Filter[0].Classification = "high"
+		}, nil},
+		{"unknown status", func(c *Config) { (*c.Lists)[1].Filter[0].Status = "new" },
+			[]problem{{"lists[1].filter[0].status", false, `unknown value "new" (did you mean "NEU"?)`}}},
+		{"unknown value without hint", func(c *Config) { (*c.Lists)[1].Filter[0].Classification = "severe" },
+			[]problem{{"lists[1].filter[0].classification", false, `unknown value "severe", expected`}}},
+		{"several problems", func(c *Config) {
+			(*c.Lists)[1].Filter[0].NoPatch = "yes"
+			(*c.Lists)[1].Filter[0].MinBaseScore = 101
+			(*c.Lists)[1].Exclude = []Filter{{Event: "old"}}
+		}, []problem{
+			{"lists[1].filter[0].no_patch", false, `unknown value "yes"`},
+			{"lists[1].filter[0].min_basescore", false, "101 is not between 0 and 100"},
+			{"lists[1].exclude[0].event", false, `unknown value "old"`},
+			{"lists[1].exclude[0]", true, "no criteria set"},
+		}},
+		{"any with other criteria", func(c *Config) { (*c.Lists)[0].Filter[0].Classification = "hoch" },
+			[]problem{{"lists[0].filter[0]", true, `"any" is set`}}},
+		{"invalid regex", func(c *Config) { (*c.Lists)[1].Filter[0].TitleRegex = StringList{"("} },
+			[]problem{{"lists[1].filter[0]", false, "error parsing regexp: missing closing )"}}},
+		{"disabled endpoint", func(c *Config) {
+			c.EnabledApiEndpoints = []string{"bund"}
+			(*c.Lists)[1].Filter[0].ApiEndpointId = "bay"
+		}, []problem{{"lists[1].filter[0].api_endpoint", true, `endpoint "bay" is not enabled`}}},
+		{"unknown and duplicate endpoints", func(c *Config) { c.EnabledApiEndpoints = []string{"bundd", "bund", "bund"} },
+			[]problem{
+				{"enabled_api_endpoints[0]", false, `unknown endpoint "bundd"`},
+				{"enabled_api_endpoints[2]", true, `endpoint "bund" is enabled more than once`},
+			}},
+		{"no sources", func(c *Config) { c.EnabledApiEndpoints = []string{} },
+			[]problem{{"enabled_api_endpoints", false, "no API endpoints or sources are enabled"}}},
+		{"fetch interval", func(c *Config) { c.ApiFetchInterval = 30 },
+			[]problem{{"api_fetch_interval", true, "30 seconds is very short"}}},
+		{"lists", func(c *Config) {
+			(*c.Lists)[1].Name = "A"
+			(*c.Lists)[2].Recipients = []string{"not an address"}
+			(*c.Lists)[0].Recipients = []string{}
+			(*c.Lists)[0].Language = "fr"
+		}, []problem{
+			{"lists[0].language", false, `unsupported language "fr"`},
+			{"lists[0].recipients", true, `list "A" has no recipients`},
+			{"lists[1].name", false, `list name "A" is not unique`},
+			{"lists[2].recipients[0]", false, `"not an address" is not a valid e-mail address`},
+		}},
+		{"named filters", func(c *Config) {
+			c.Filters = map[string]Filter{"a": {Ref: StringList{"b"}}, "b": {Ref: StringList{"a"}}}
+			(*c.Lists)[1].Filter[0].Ref = StringList{"c"}
+		}, []problem{
+			{"filters.a", false, "filters reference each other: a -> b -> a"},
+			{"lists[1].filter[0]", false, "unknown filter id 'c'"},
+		}},
+		{"recipients", func(c *Config) {
+			c.Recipients = []Recipient{
+				{Address: "r@example.org", Lists: []string{"A"}, Language: "fr"},
+				{Address: "R@example.org", Lists: []string{"A"}, Channels: []RecipientChannel{{Type: "sms"}, {Type: "webhook"}}},
+			}
+		}, []problem{
+			{"recipients[0].language", false, `unsupported language "fr"`},
+			{"recipients[1].channels[0].type", false, `unknown channel type "sms"`},
+			{"recipients[1].channels[1].url", false, "webhook channel without url"},
+			{"recipients[1].address", false, "recipient R@example.org is configured more than once"},
+		}},
+		{"initial password", func(c *Config) { c.SmtpConfiguration.Password = NewConfig().SmtpConfiguration.Password },
+			[]problem{{"smtp.password", true, "the password of the initial configuration is still set"}}},
+		// secrets
+		{"password and password_file", func(c *Config) { c.SmtpConfiguration.PasswordFile = "/run/secrets/smtp" },
+			[]problem{{"smtp.password_file", false, "only one of password, password_file can be set"}}},
+		{"portal secret_file and secret_command", func(c *Config) {
+			c.Portal.SecretFile = "/run/secrets/portal"
+			c.Portal.SecretCommand = "pass show portal"
+		}, []problem{{"portal.secret_command", false, "only one of secret_file, secret_command can be set"}}},
+		{"proxy and proxy_command", func(c *Config) {
+			c.HttpConfiguration.Proxy = "http://proxy:3128"
+			c.HttpConfiguration.ProxyCommand = "echo http://proxy:3128"
+		}, []problem{{"http.proxy_command", false, "only one of proxy, proxy_command can be set"}}},
+		{"webhook url and url_file", func(c *Config) {
+			c.Recipients = webhook(RecipientChannel{Type: "webhook", Url: "https://chat.example.org/hook", UrlFile: "hook.txt"})
+		}, []problem{{"recipients[0].channels[0].url_file", false, "only one of url, url_file can be set"}}},
+		{"webhook url_file that doesn't exist", func(c *Config) {
+			c.Recipients = webhook(RecipientChannel{Type: "webhook", UrlFile: "/nonexistent/hook.txt"})
+		}, []problem{{"recipients[0].channels[0].url_file", false, "open /nonexistent/hook.txt"}}},
+		{"webhook token_command that fails", func(c *Config) {
+			c.Recipients = webhook(RecipientChannel{Type: "webhook", Url: "${WID_TEST_SECRET}", TokenCommand: "exit 3"})
+		}, []problem{{"recipients[0].channels[0].token_command", false, ""}}},
+		{"environment variable", func(c *Config) { c.SmtpConfiguration.Password = "${WID_TEST_SECRET}" }, nil},
+		{"password containing ${", func(c *Config) { c.SmtpConfiguration.Password = "pa${WID_TEST_UNSET}ss" }, nil},
+		{"environment variable that isn't set", func(c *Config) { c.SmtpConfiguration.Password = "${WID_TEST_UNSET}" },
+			[]problem{{"smtp.password", false, "environment variable WID_TEST_UNSET is not set"}}},
+		{"portal", func(c *Config) {
+			c.Portal.Listen = "127.0.0.1:8080"
+			c.Portal.Secret = "short"
+		}, []problem{{"portal", false, "the secret of the portal must have at least 16 characters"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.change(&c)
+			_, problems := validateConfig(&c)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("got %v problems, want %v:\n%v", len(problems), len(tt.want), problems)
+			}
+			for i, p := range problems {
+				w := tt.want[i]
+				if p.Path != w.path || p.Warning != w.warning || !strings.HasPrefix(p.Message, w.message) {
+					t.Errorf("got %q (warning: %v), want %v: %v... (warning: %v)", p, p.Warning, w.path, w.message, w.warning)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateConfigResolvesSecrets(t *testing.T) {
+	logger = NewLogger(0)
+	t.Setenv("WID_TEST_SECRET", "from env")
+	c := validConfig()
+	c.SmtpConfiguration.Password = "${WID_TEST_SECRET}"
+	c.HttpConfiguration.ProxyCommand = "echo http://proxy.example.org:3128"
+	c.Recipients = []Recipient{{Address: "w@example.org", Filter: []Filter{{Any: true}}, Channels: []RecipientChannel{
+		{Type: "webhook", UrlCommand: "printf https://chat.example.org/hook", Token: "${WID_TEST_SECRET}"},
+		{Type: "webhook", Url: "https://chat.example.org/${WID_TEST_SECRET}", Token: "x${WID_TEST_UNSET}x"},
+	}}}
+	if _, problems := validateConfig(&c); len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	for _, s := range []struct{ got string; want string }{
+		{c.SmtpConfiguration.Password, "from env"},
+		{c.HttpConfiguration.Proxy, "http://proxy.example.org:3128"},
+		{c.Recipients[0].Channels[0].Url, "https://chat.example.org/hook"},
+		{c.Recipients[0].Channels[0].Token, "from env"},
+		// only whole values are references
+		{c.Recipients[0].Channels[1].Url, "https://chat.example.org/${WID_TEST_SECRET}"},
+		{c.Recipients[0].Channels[1].Token, "x${WID_TEST_UNSET}x"},
+	} {
+		if s.got != s.want {
+			t.Errorf("got %q, want %q", s.got, s.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Julian Müller (ChaoticByte)
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// The payload works with the incoming webhooks of most chat services
+// (Slack, Mattermost, Rocket.Chat, ...)
+type webhookPayload struct {
+	Text string `json:"text"`
+	Subject string `json:"subject"`
+	Body string `json:"body"`
+}
+
+// webhookText formats the mail for the chat service
+func webhookText(mc *MailContent, format string) string {
+	if format == "" {
+		format = "text"
+		if mc.Markdown != "" {
+			format = "markdown"
+		}
+	}
+	switch format {
+	case "markdown":
+		if mc.Markdown == "" {
+			break
+		}
+		return "**" + markdownSpecialChars.ReplaceAllString(mc.Subject, "\\$1") + "**\n\n" + mc.Markdown
+	case "slack":
+		if mc.Markdown == "" {
+			return "*" + slackEscape(mc.Subject) + "*\n\n" + slackEscape(mc.Body)
+		}
+		return "*" + slackEscape(mc.Subject) + "*\n\n" + markdownToSlack(mc.Markdown)
+	}
+	return mc.Subject + "\n\n" + mc.Body
+}
+
+// sendWebhook posts each mail as JSON to the url of the channel
+func sendWebhook(client *HttpClient, c RecipientChannel, mails []*MailContent) error {
+	header := http.Header{}
+	if c.Token != "" {
+		header.Set("Authorization", "Bearer " + c.Token)
+	}
+	for _, mc := range mails {
+		if mc == nil { continue }
+		data, err := json.Marshal(webhookPayload{
+			Text: webhookText(mc, c.Format),
+			Subject: mc.Subject,
+			Body: mc.Body,
+		})
+		if err != nil { return err }
+		if err = client.post(c.Url, "application/json", data, header); err != nil { return err }
+	}
+	return nil
+}
@@ -4,9 +4,6 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 )
@@ -15,18 +12,19 @@ import (
 var apiEndpoints []ApiEndpoint = []ApiEndpoint{
 	{
 		Id: "bay",
+		Name: "LSI Bayern",
 		EndpointUrl: "https://wid.lsi.bayern.de/content/public/securityAdvisory",
 		PortalUrl: "https://wid.lsi.bayern.de/portal/wid/securityadvisory",
 	},
 	{
 		Id: "bund",
+		Name: "BSI",
 		EndpointUrl: "https://wid.cert-bund.de/content/public/securityAdvisory",
 		PortalUrl: "https://wid.cert-bund.de/portal/wid/securityadvisory",
 	},
 }
 
 const PUBLISHED_TIME_FORMAT = "2006-01-02T15:04:05.999-07:00"
-const USER_AGENT = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0"
 
 var defaultParams = []string{
 	"size=1000", // max backlog
@@ -36,57 +34,42 @@ var defaultParams = []string{
 
 type ApiEndpoint struct {
 	Id string
+	Name string // of the publisher
 	EndpointUrl string
 	PortalUrl string
 }
 
-func (e ApiEndpoint) getNotices(since time.Time) ([]WidNotice, time.Time, error) {
+func (e ApiEndpoint) sourceId() string {
+	return e.Id
+}
+
+func (e ApiEndpoint) sourceName() string {
+	return "API endpoint '" + e.Id + "'"
+}
+
+func (e ApiEndpoint) getNotices(client *HttpClient, since time.Time) ([]WidNotice, time.Time, error) {
 	// returns a slice of WidNotice and the 'published' field of the last notice, and the error (or nil)
+	// (notices published before since are included)
 	var notices []WidNotice = []WidNotice{}
-	var err error
 	params := defaultParams
 	// params = append(params, "publishedFromFilter=" + publishedFrom.Format(PUBLISHED_FROM_FILTER_TIME_FORMAT))
 	// ^ looks like the API is f***ed, 'publishedFromFilter=...' does only factor in the day (-2h because of the
 	// timezone), not the time of the day - echte Deutsche Wertarbeit mal wieder am Start
 	// -> we have to filter by hand (see below)
 	url := e.EndpointUrl + "?" + strings.Join(params, "&")
-	req, _ := http.NewRequest(http.MethodGet, url, nil)
-	req.Header.Set("User-Agent", USER_AGENT)
-	client := http.Client{}
-	res, err := client.Do(req)
-	if err == nil {
-		if res.StatusCode == 200 {
-			resBody, err := io.ReadAll(res.Body)
-			if err != nil { return []WidNotice{}, since, err }
-			var decodedData map[string]interface{}
-			if err = json.Unmarshal(resBody, &decodedData); err != nil {
-				return []WidNotice{}, since, err
-			}
-			notices = parseApiResponse(decodedData, e)
-		} else {
-			logger.error(fmt.Sprintf("Get \"%v\": %v\n", url, res.Status))
-			return []WidNotice{}, since, err
-		}
-	} else {
-		return []WidNotice{}, since, err
-	}
-	if len(notices) > 0 {
-		// And here the filtering begins. yay -.-
-		noticesFiltered := []WidNotice{}
-		lastPublished := since
-		for _, n := range notices {
-			if n.Published.After(since) {
-				noticesFiltered = append(noticesFiltered, n)
-				// while we are at it, we can also find lastPublished
-				if n.Published.After(lastPublished) {
-					lastPublished = n.Published
-				}
-			}
-		}
-		return noticesFiltered, lastPublished, nil
-	} else {
+	resBody, modified, err := client.getIfModified(url)
+	if err != nil { return []WidNotice{}, since, err }
+	if !modified {
+		logger.debug("Endpoint '" + e.Id + "' reports no changes")
 		return []WidNotice{}, since, nil
 	}
+	var decodedData map[string]interface{}
+	if err = json.Unmarshal(resBody, &decodedData); err != nil {
+		return []WidNotice{}, since, err
+	}
+	notices = parseApiResponse(decodedData, e)
+	// the filtering happens when looking for revisions, see detectRevisions
+	return notices, latestPublished(notices, since), nil
 }
 
 func parseApiResponse(data map[string]interface{}, apiEndpoint ApiEndpoint) []WidNotice {